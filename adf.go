@@ -0,0 +1,401 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// adfNode is a node of the Atlassian Document Format, which the v3 API and rich text fields use in place of wiki markup
+type adfNode struct {
+	Type    string         `json:"type"`
+	Text    string         `json:"text,omitempty"`
+	Attrs   map[string]any `json:"attrs,omitempty"`
+	Marks   []adfNode      `json:"marks,omitempty"`
+	Content []adfNode      `json:"content,omitempty"`
+}
+
+// ADF's panel types, as the wiki macros they match
+var adfPanels = map[string]string{
+	"info":    "info",
+	"note":    "note",
+	"warning": "warning",
+	"error":   "warning",
+	"success": "tip",
+	"tip":     "tip",
+	"custom":  "panel",
+}
+
+// ParseJiraDocument parses Jira text, which is wiki markup, or ADF when it is an ADF document
+func ParseJiraDocument(input string) *WikiNode {
+	if strings.HasPrefix(strings.TrimSpace(input), "{") {
+		if doc, err := ParseADF([]byte(input)); err == nil {
+			return doc
+		}
+	}
+	return ParseWiki(input)
+}
+
+// ParseADF parses an ADF document into the same tree wiki markup is parsed into
+func ParseADF(raw []byte) (*WikiNode, error) {
+	doc := adfNode{}
+	err := json.Unmarshal(raw, &doc)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to unmarshal ADF")
+	}
+	if doc.Type != "doc" {
+		return nil, errors.New("Not an ADF document, its type is `" + doc.Type + "`")
+	}
+	return &WikiNode{Kind: WikiDocument, Children: adfChildren(doc)}, nil
+}
+
+func (n adfNode) attr(key string) string {
+	switch v := n.Attrs[key].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+func adfChildren(n adfNode) (nodes []*WikiNode) {
+	for _, c := range n.Content {
+		nodes = append(nodes, adfConvert(c)...)
+	}
+	return nodes
+}
+
+// adfText joins the text within a node, for code blocks
+func adfText(n adfNode) string {
+	b := strings.Builder{}
+	for _, c := range n.Content {
+		if c.Type == "hardBreak" {
+			b.WriteString("\n")
+		}
+		b.WriteString(c.Text)
+	}
+	return b.String()
+}
+
+func adfConvert(n adfNode) []*WikiNode {
+
+	switch n.Type {
+	case "paragraph":
+		return []*WikiNode{{Kind: WikiParagraph, Children: adfChildren(n)}}
+	case "heading":
+		level, _ := strconv.Atoi(n.attr("level"))
+		return []*WikiNode{{Kind: WikiHeading, Level: min(max(level, 1), 6), Children: adfChildren(n)}}
+	case "bulletList", "taskList", "decisionList":
+		return []*WikiNode{{Kind: WikiList, Children: adfChildren(n)}}
+	case "orderedList":
+		return []*WikiNode{{Kind: WikiList, Ordered: true, Children: adfChildren(n)}}
+	case "listItem", "taskItem", "decisionItem":
+		children := adfChildren(n)
+		if len(children) == 0 || children[0].Kind != WikiParagraph { // Task items hold their text directly
+			inline := []*WikiNode{}
+			for len(children) > 0 && children[0].Kind != WikiParagraph && children[0].Kind != WikiList {
+				inline = append(inline, children[0])
+				children = children[1:]
+			}
+			children = append([]*WikiNode{{Kind: WikiParagraph, Children: inline}}, children...)
+		}
+		if n.Type == "taskItem" {
+			marker := "[ ] "
+			if n.attr("state") == "DONE" {
+				marker = "[x] "
+			}
+			children[0].Children = append([]*WikiNode{{Kind: WikiText, Text: marker}}, children[0].Children...)
+		}
+		return []*WikiNode{{Kind: WikiListItem, Children: children}}
+	case "codeBlock":
+		return []*WikiNode{{Kind: WikiCode, Text: adfText(n), Params: map[string]string{"language": n.attr("language")}}}
+	case "blockquote":
+		return []*WikiNode{{Kind: WikiQuote, Children: adfChildren(n)}}
+	case "panel":
+		name, ok := adfPanels[n.attr("panelType")]
+		if !ok {
+			name = "panel"
+		}
+		return []*WikiNode{{Kind: WikiPanel, Name: name, Params: map[string]string{}, Children: adfChildren(n)}}
+	case "expand", "nestedExpand":
+		return []*WikiNode{{Kind: WikiPanel, Name: "panel", Params: map[string]string{"title": n.attr("title")}, Children: adfChildren(n)}}
+	case "rule":
+		return []*WikiNode{{Kind: WikiRule}}
+	case "table":
+		return []*WikiNode{{Kind: WikiTable, Children: adfChildren(n)}}
+	case "tableRow":
+		return []*WikiNode{{Kind: WikiRow, Children: adfChildren(n)}}
+	case "tableHeader", "tableCell":
+		return []*WikiNode{{Kind: WikiCell, Header: n.Type == "tableHeader", Children: adfChildren(n)}}
+	case "mediaSingle", "mediaGroup":
+		return []*WikiNode{{Kind: WikiParagraph, Children: adfChildren(n)}}
+	case "media":
+		name := n.attr("alt")
+		if name == "" {
+			name = n.attr("url")
+		}
+		if name == "" {
+			return nil
+		}
+		params := map[string]string{}
+		for _, key := range []string{"width", "height"} {
+			if v := n.attr(key); v != "" {
+				params[key] = v
+			}
+		}
+		return []*WikiNode{{Kind: WikiImage, Text: name, Params: params}}
+	case "text":
+		return []*WikiNode{adfMarks(n)}
+	case "hardBreak":
+		return []*WikiNode{{Kind: WikiBreak}}
+	case "mention":
+		return []*WikiNode{{Kind: WikiMention, Text: n.attr("id")}}
+	case "emoji":
+		text := n.attr("text")
+		if text == "" {
+			text = n.attr("shortName")
+		}
+		return []*WikiNode{{Kind: WikiText, Text: text}}
+	case "inlineCard", "blockCard", "embedCard":
+		link := &WikiNode{Kind: WikiLink, Text: n.attr("url")}
+		if n.Type == "inlineCard" {
+			return []*WikiNode{link}
+		}
+		return []*WikiNode{{Kind: WikiParagraph, Children: []*WikiNode{link}}}
+	case "status":
+		return []*WikiNode{{Kind: WikiMonospace, Text: n.attr("text")}}
+	case "date":
+		ms, err := strconv.ParseInt(n.attr("timestamp"), 10, 64)
+		if err != nil {
+			return nil
+		}
+		return []*WikiNode{{Kind: WikiText, Text: DateFormat(time.UnixMilli(ms).UTC())}}
+	}
+
+	return adfChildren(n) // Anything else for what it holds
+}
+
+// adfMarks applies a text node's marks, the first being outermost
+func adfMarks(n adfNode) *WikiNode {
+
+	for _, mark := range n.Marks {
+		if mark.Type == "code" { // Nothing else applies within code
+			node := &WikiNode{Kind: WikiMonospace, Text: n.Text}
+			for _, m := range n.Marks {
+				if m.Type == "link" {
+					return &WikiNode{Kind: WikiLink, Text: m.attr("href"), Children: []*WikiNode{node}}
+				}
+			}
+			return node
+		}
+	}
+
+	node := &WikiNode{Kind: WikiText, Text: n.Text}
+
+	for i := len(n.Marks) - 1; i >= 0; i-- {
+		mark := n.Marks[i]
+		switch mark.Type {
+		case "strong":
+			node = &WikiNode{Kind: WikiStrong, Children: []*WikiNode{node}}
+		case "em":
+			node = &WikiNode{Kind: WikiEmphasis, Children: []*WikiNode{node}}
+		case "strike":
+			node = &WikiNode{Kind: WikiDeleted, Children: []*WikiNode{node}}
+		case "underline":
+			node = &WikiNode{Kind: WikiInserted, Children: []*WikiNode{node}}
+		case "subsup":
+			kind := WikiSuperscript
+			if mark.attr("type") == "sub" {
+				kind = WikiSubscript
+			}
+			node = &WikiNode{Kind: kind, Children: []*WikiNode{node}}
+		case "textColor":
+			node = &WikiNode{Kind: WikiColor, Params: map[string]string{"color": mark.attr("color")}, Children: []*WikiNode{node}}
+		case "link":
+			node = &WikiNode{Kind: WikiLink, Text: mark.attr("href"), Children: []*WikiNode{node}}
+		}
+	}
+
+	return node
+}
+
+// ADF's panel types for the wiki macros, the reverse of adfPanels
+var adfPanelTypes = map[string]string{
+	"info":    "info",
+	"note":    "note",
+	"warning": "warning",
+	"tip":     "success",
+	"panel":   "info",
+}
+
+// RenderADF renders a parsed document as an ADF document
+func RenderADF(doc *WikiNode) ([]byte, error) {
+	output, err := json.Marshal(adfNode{Type: "doc", Attrs: map[string]any{"version": 1}, Content: adfBlocks(doc.Children)})
+	return output, errors.Wrap(err, "Failed to marshal ADF")
+}
+
+func adfBlocks(nodes []*WikiNode) (blocks []adfNode) {
+	for _, n := range nodes {
+		blocks = append(blocks, adfBlock(n)...)
+	}
+	return blocks
+}
+
+// adfBlock renders a block, images in a paragraph following it as blocks of their own, as ADF only has them so
+func adfBlock(n *WikiNode) []adfNode {
+
+	switch n.Kind {
+	case WikiParagraph:
+		inline, images := []*WikiNode{}, []adfNode{}
+		for _, c := range n.Children {
+			if c.Kind == WikiImage {
+				images = append(images, adfImage(c))
+			} else {
+				inline = append(inline, c)
+			}
+		}
+		content := adfInline(inline, nil)
+		if len(content) == 0 && len(images) > 0 {
+			return images
+		}
+		return append([]adfNode{{Type: "paragraph", Content: content}}, images...)
+	case WikiHeading:
+		return []adfNode{{Type: "heading", Attrs: map[string]any{"level": n.Level}, Content: adfInline(n.Children, nil)}}
+	case WikiList:
+		list := adfNode{Type: "bulletList"}
+		if n.Ordered {
+			list.Type = "orderedList"
+		}
+		for _, item := range n.Children {
+			content := adfBlocks(item.Children)
+			if len(content) == 0 {
+				content = []adfNode{{Type: "paragraph"}}
+			}
+			list.Content = append(list.Content, adfNode{Type: "listItem", Content: content})
+		}
+		return []adfNode{list}
+	case WikiCode, WikiNoFormat:
+		code := adfNode{Type: "codeBlock"}
+		if language := n.Params["language"]; language != "" {
+			code.Attrs = map[string]any{"language": language}
+		}
+		if n.Text != "" {
+			code.Content = []adfNode{{Type: "text", Text: n.Text}}
+		}
+		return []adfNode{code}
+	case WikiQuote:
+		return []adfNode{{Type: "blockquote", Content: adfBlocks(n.Children)}}
+	case WikiPanel:
+		panelType, ok := adfPanelTypes[n.Name]
+		if !ok {
+			panelType = "info"
+		}
+		content := adfBlocks(n.Children)
+		if title := n.Params["title"]; title != "" {
+			content = append([]adfNode{{Type: "paragraph", Content: []adfNode{{Type: "text", Text: title, Marks: []adfNode{{Type: "strong"}}}}}}, content...)
+		}
+		return []adfNode{{Type: "panel", Attrs: map[string]any{"panelType": panelType}, Content: content}}
+	case WikiTable:
+		table := adfNode{Type: "table"}
+		for _, row := range n.Children {
+			r := adfNode{Type: "tableRow"}
+			for _, cell := range row.Children {
+				c := adfNode{Type: "tableCell", Content: adfBlocks(cell.Children)}
+				if cell.Header {
+					c.Type = "tableHeader"
+				}
+				if len(c.Content) == 0 {
+					c.Content = []adfNode{{Type: "paragraph"}}
+				}
+				r.Content = append(r.Content, c)
+			}
+			table.Content = append(table.Content, r)
+		}
+		return []adfNode{table}
+	case WikiRule:
+		return []adfNode{{Type: "rule"}}
+	}
+
+	return []adfNode{{Type: "paragraph", Content: adfInline([]*WikiNode{n}, nil)}}
+}
+
+// adfImage renders an image, by URL, or by the filename of an attachment it is expected to be uploaded as
+func adfImage(n *WikiNode) adfNode {
+	attrs := map[string]any{"type": "external", "url": n.Text, "alt": n.Text}
+	for _, key := range []string{"width", "height"} {
+		if v, err := strconv.Atoi(n.Params[key]); err == nil {
+			attrs[key] = v
+		}
+	}
+	return adfNode{Type: "mediaSingle", Content: []adfNode{{Type: "media", Attrs: attrs}}}
+}
+
+// adfInline flattens formatting into marks on text, the way ADF holds it
+func adfInline(nodes []*WikiNode, marks []adfNode) (content []adfNode) {
+
+	mark := func(n *WikiNode, m adfNode) {
+		content = append(content, adfInline(n.Children, append(slices.Clone(marks), m))...)
+	}
+
+	for _, n := range nodes {
+		switch n.Kind {
+		case WikiText, WikiEscape:
+			if n.Text != "" {
+				content = append(content, adfNode{Type: "text", Text: n.Text, Marks: marks})
+			}
+		case WikiBreak:
+			content = append(content, adfNode{Type: "hardBreak"})
+		case WikiStrong:
+			mark(n, adfNode{Type: "strong"})
+		case WikiEmphasis, WikiCitation:
+			mark(n, adfNode{Type: "em"})
+		case WikiDeleted:
+			mark(n, adfNode{Type: "strike"})
+		case WikiInserted:
+			mark(n, adfNode{Type: "underline"})
+		case WikiSuperscript:
+			mark(n, adfNode{Type: "subsup", Attrs: map[string]any{"type": "sup"}})
+		case WikiSubscript:
+			mark(n, adfNode{Type: "subsup", Attrs: map[string]any{"type": "sub"}})
+		case WikiColor:
+			mark(n, adfNode{Type: "textColor", Attrs: map[string]any{"color": n.Params["color"]}})
+		case WikiMonospace:
+			code := []adfNode{{Type: "code"}}
+			for _, m := range marks { // Code only goes with links
+				if m.Type == "link" {
+					code = append(code, m)
+				}
+			}
+			if n.Text != "" {
+				content = append(content, adfNode{Type: "text", Text: n.Text, Marks: code})
+			}
+		case WikiLink:
+			if len(n.Children) == 0 && len(marks) == 0 {
+				content = append(content, adfNode{Type: "inlineCard", Attrs: map[string]any{"url": n.Text}})
+				continue
+			}
+			children := n.Children
+			if len(children) == 0 {
+				children = []*WikiNode{{Kind: WikiText, Text: n.Text}}
+			}
+			content = append(content, adfInline(children, append(slices.Clone(marks), adfNode{Type: "link", Attrs: map[string]any{"href": n.Text}}))...)
+		case WikiMention:
+			content = append(content, adfNode{Type: "mention", Attrs: map[string]any{"id": n.Text}})
+		case WikiAttachment:
+			label := n.Children
+			if len(label) == 0 {
+				label = []*WikiNode{{Kind: WikiText, Text: n.Text}}
+			}
+			content = append(content, adfInline(label, marks)...)
+		case WikiImage:
+			content = append(content, adfNode{Type: "text", Text: n.Text, Marks: marks}) // Only within cells and the like, where media can't go
+		}
+	}
+
+	return content
+}
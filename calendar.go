@@ -27,13 +27,14 @@ type CalendarConfig struct {
 			Enabled     bool    `json:"enabled"`
 			LengthHours float64 `json:"length_hours"`
 		} `json:"max_duration"`
-		Titles    []string `json:"titles"`
-		PastDates bool     `json:"past_dates"`
+		Titles    []*Matcher `json:"titles"` // Plain strings match titles exactly
+		PastDates bool       `json:"past_dates"`
 	} `json:"exclusions"`
 	TimeZones []struct {
 		From string `json:"from"`
 		To   string `json:"to"`
 	} `json:"timezones"`
+	Properties *PropertyOptions `json:"properties"` // Falls back to the Jira options' properties if unset
 }
 
 func (c *CalendarConfig) Process(wg *errgroup.Group) (err error) {
@@ -86,6 +87,11 @@ func (c *CalendarConfig) Process(wg *errgroup.Group) (err error) {
 
 	days := map[string][]string{}
 
+	propertyOptions := c.Properties
+	if propertyOptions == nil {
+		propertyOptions = &config.Jira.Options.Properties
+	}
+
 	dateFormat := "2006_01_02"
 
 	for _, e := range calendarEvents {
@@ -93,7 +99,7 @@ func (c *CalendarConfig) Process(wg *errgroup.Group) (err error) {
 		duration := e.End.Sub(*e.Start)
 
 		// Excluded titles
-		if slices.Contains(c.Exclusions.Titles, e.Summary) {
+		if MatchAny(c.Exclusions.Titles, MatchTarget{Name: e.Summary, Legacy: e.Summary}) {
 			continue
 		}
 
@@ -114,16 +120,16 @@ func (c *CalendarConfig) Process(wg *errgroup.Group) (err error) {
 
 		if e.Status == "CANCELED" || strings.HasPrefix(e.Summary, "Canceled: ") {
 			text = append(text,
-				"- CANCELED [[Calendar Event]] - "+e.Summary,
+				"- CANCELED [[Calendar Event]] - "+LogseqText(e.Summary),
 			)
 		} else {
 			if e.End.Before(time.Now()) || c.AllEventsDone {
 				text = append(text,
-					"- DONE [[Calendar Event]] - "+e.Summary,
+					"- DONE [[Calendar Event]] - "+LogseqText(e.Summary),
 				)
 			} else {
 				text = append(text,
-					"- WAITING [[Calendar Event]] - "+e.Summary,
+					"- WAITING [[Calendar Event]] - "+LogseqText(e.Summary),
 				)
 			}
 		}
@@ -141,37 +147,39 @@ func (c *CalendarConfig) Process(wg *errgroup.Group) (err error) {
 
 		slog.Debug(e.Summary + " - " + baseId + " " + recurranceId.Format("20060102T150405"))
 
+		properties := []Property{
+			{"status", e.Status},
+			{"id", deterministicGUID(baseId + recurranceId.Format("20060102T150405"))},
+		}
+
+		if e.Organizer != nil {
+			properties = append(properties, Property{"organizer", e.Organizer.Value})
+		}
+
 		text = append(text,
 			"  SCHEDULED: <"+e.Start.Local().Format("2006-01-02 Mon 15:04")+">",
-			"  status:: "+e.Status,
-			"  id:: "+deterministicGUID(baseId+recurranceId.Format("20060102T150405")),
+		)
+
+		text = append(text, PrefixProperties(propertyOptions.Apply(properties), "  ")...)
+
+		text = append(text,
 			"  :AGENDA:",
 			"  estimated: "+strconv.Itoa(durationMinutes)+"m",
 			"  :END:",
 		)
 
-		if e.Organizer != nil {
-			text = append(text,
-				"  organizer:: "+e.Organizer.Value,
-			)
-		}
-
 		days[page] = append(days[page], text...)
 
 	}
 
 	for k, d := range days {
 		if time.Now().Format(dateFormat) <= k || c.Exclusions.PastDates {
-			err = WriteFile(
-				path.Join(
-					*config.Jira.Options.Outputs.Logseq.LogseqRoot,
-					"pages",
-					"calendar",
-					c.Title,
-					PageNameToFileName("calendar/"+c.Title+"/"+k)+".md"),
+			err = WritePageIn(
+				path.Join("calendar", c.Title),
+				"calendar/"+c.Title+"/"+k,
 				[]byte(strings.Join(d, "\n")))
 			if err != nil {
-				return errors.Wrap(err, "Failed in WriteFile for "+k)
+				return errors.Wrap(err, "Failed in WritePageIn for "+k)
 			}
 		}
 	}
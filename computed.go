@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira/v2/cloud"
+	"github.com/pkg/errors"
+)
+
+// ExprName is how a property is referred to in expressions, date-due becomes date_due
+func ExprName(property string) string {
+	return strings.ReplaceAll(property, "-", "_")
+}
+
+// IssueEnv returns the variables computed_fields are evaluated with.
+// customFields are the issue's translated custom fields, which are added by their property names.
+func IssueEnv(project *JiraProject, issue *jira.Issue, dueDate *time.Time, customFields []Property) (map[string]any, error) {
+
+	fullIssue, _, err, _ := GetIssue(project, issue, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed in GetIssue")
+	}
+	if fullIssue == nil {
+		fullIssue = issue
+	}
+
+	fields := map[string]any{} // Raw fields, custom ones included, as Jira returns them
+	raw, err := json.Marshal(fullIssue.Fields)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to marshal fields of "+issue.Key)
+	}
+	err = json.Unmarshal(raw, &fields)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to unmarshal fields of "+issue.Key)
+	}
+
+	f := fullIssue.Fields
+
+	env := map[string]any{
+		"key":           issue.Key,
+		"project":       *project.Key,
+		"summary":       f.Summary,
+		"status":        f.Status.Name,
+		"status_simple": SimplifyStatus(project, fullIssue),
+		"type":          JiraTypeSubstitute(project, fullIssue),
+		"created":       time.Time(f.Created),
+		"updated":       time.Time(f.Updated),
+		"date_due":      nil,
+		"assignee":      nil,
+		"reporter":      nil,
+		"priority":      nil,
+		"labels":        []any{},
+		"today":         exprDay(startTime),
+		"now":           startTime,
+		"fields":        fields,
+	}
+
+	if dueDate != nil {
+		env["date_due"] = *dueDate
+	}
+	if f.Assignee != nil {
+		env["assignee"] = PersonName(f.Assignee.DisplayName)
+	}
+	if f.Reporter != nil {
+		env["reporter"] = PersonName(f.Reporter.DisplayName)
+	}
+	if f.Priority != nil {
+		env["priority"] = f.Priority.Name
+	}
+	for _, label := range f.Labels {
+		env["labels"] = append(env["labels"].([]any), label)
+	}
+
+	for _, customField := range project.Options.CustomFields { // Configured but unset on this issue is null, rather than unknown
+		env[ExprName(*customField.To)] = nil
+	}
+	for _, computed := range project.Options.ComputedFields {
+		if computed.To != nil {
+			env[ExprName(*computed.To)] = nil
+		}
+	}
+
+	for _, p := range customFields {
+		env[ExprName(p.Key)] = exprValue(p.Key, p.Value)
+	}
+
+	return env, nil
+}
+
+// exprValue turns a property value back into a date or number where it is one, so it can be compared and added up.
+// Sortable dates, like 20240102, read as dates rather than numbers.
+func exprValue(key string, value string) any {
+	if strings.HasSuffix(key, "-sortable") {
+		if t, err := ParseExprDate(value); err == nil {
+			return t
+		}
+		return value
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	return value
+}
+
+// ComputeFields evaluates the computed_fields of a project, in order, each seeing the results before it.
+// Fields that come out null or empty are left out.
+func ComputeFields(project *JiraProject, issue *jira.Issue, dueDate *time.Time, customFields []Property) (output []Property, err error) {
+
+	if len(project.Options.ComputedFields) == 0 {
+		return nil, nil
+	}
+
+	env, err := IssueEnv(project, issue, dueDate, customFields)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, computed := range project.Options.ComputedFields {
+		if computed.To == nil || computed.Expr == nil {
+			continue
+		}
+
+		v, err := EvalExpr(*computed.Expr, env)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to compute "+*computed.To+" for "+issue.Key)
+		}
+
+		env[ExprName(*computed.To)] = v
+
+		value := ""
+		switch v := v.(type) {
+		case time.Time:
+			value = DateFormat(v)
+			if *project.Options.Outputs.Logseq.LinkDates {
+				value = "[[" + value + "]]"
+			}
+		default:
+			value = exprString(v)
+		}
+
+		if value != "" {
+			output = append(output, Property{*computed.To, value})
+		}
+	}
+
+	return output, nil
+}
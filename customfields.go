@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/pkg/errors"
+)
+
+// CustomFieldValues holds an issue's configured custom fields by ID, as decoded from JSON
+type CustomFieldValues map[string]any
+
+// String returns a custom field's value if it is a non-empty string
+func (v CustomFieldValues) String(id string) (string, bool) {
+	val, ok := v[id].(string)
+	return val, ok && val != "" && val != "<nil>"
+}
+
+// customFieldConverters render a custom field's value as property values for `as`, nil to emit nothing.
+// The first value is emitted under `to`, any second one under `to` with a -sortable suffix.
+var customFieldConverters = map[string]func(project *JiraProject, val any, path *string) ([]string, error){
+	"date_sortable": convertDate,
+	"option":        convertOption,
+	"options":       convertOptions,
+	"user":          convertUser,
+	"users":         convertUsers,
+	"number":        convertNumber,
+	"datetime":      convertDatetime,
+	"url":           convertURL,
+	"labels":        convertLabels,
+	"cascading":     convertCascading,
+	"json-path":     convertJSONPath,
+}
+
+// ConvertCustomField renders a custom field's value as properties, according to its `as`
+func ConvertCustomField(project *JiraProject, to string, as *string, path *string, val any) (output []Property, err error) {
+
+	var values []string
+
+	if as == nil {
+		s, ok := val.(string)
+		if !ok {
+			slog.Debug("Dropping custom field " + to + " as it isn't a string, set `as` to convert it")
+			return nil, nil
+		}
+		values = []string{LogseqValue(s)}
+	} else {
+		convert, ok := customFieldConverters[*as]
+		if !ok {
+			return nil, errors.New("Unknown custom field conversion `" + *as + "` for " + to)
+		}
+		values, err = convert(project, val, path)
+		if err != nil { // One odd value shouldn't cost the whole issue
+			slog.Warn("Dropping custom field " + to + ", failed to convert it as " + *as + ": " + err.Error())
+			return nil, nil
+		}
+	}
+
+	for i, v := range values {
+		if v == "" {
+			continue
+		}
+		switch i {
+		case 0:
+			output = append(output, Property{to, v})
+		case 1:
+			output = append(output, Property{to + "-sortable", v})
+		}
+	}
+
+	return output, nil
+}
+
+func convertDate(project *JiraProject, val any, _ *string) ([]string, error) {
+	s, ok := val.(string)
+	if !ok || s == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed in time.Parse")
+	}
+	linked := "" // Only the sortable form is emitted unless dates are linked
+	if *project.Options.Outputs.Logseq.LinkDates {
+		linked = "[[" + DateFormat(date) + "]]"
+	}
+	return []string{linked, date.Format("20060102")}, nil
+}
+
+func convertDatetime(project *JiraProject, val any, _ *string) ([]string, error) {
+	s, ok := val.(string)
+	if !ok || s == "" {
+		return nil, nil
+	}
+	t, err := parseDatetime(s)
+	if err != nil {
+		return nil, err
+	}
+	display := DateFormat(t)
+	if *project.Options.Outputs.Logseq.LinkDates {
+		display = "[[" + display + "]]"
+	}
+	return []string{display + " " + t.Format("15:04"), t.Format("20060102")}, nil
+}
+
+// datetimeLayouts are the timestamps Jira gives, fractional seconds are accepted whether or not a layout has them
+var datetimeLayouts = []string{
+	"2006-01-02T15:04:05-0700", // 2024-05-10T13:46:45.585-0500
+	time.RFC3339,               // 2024-05-10T13:46:45Z
+}
+
+func parseDatetime(s string) (t time.Time, err error) {
+	for _, layout := range datetimeLayouts {
+		t, err = time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return t, errors.Wrap(err, "Failed in time.Parse")
+}
+
+// optionValue returns the value of a select list option, {"value": "Red", "id": "1"}
+func optionValue(val any) string {
+	m, ok := val.(map[string]any)
+	if !ok {
+		return ""
+	}
+	s, _ := m["value"].(string)
+	return LogseqValue(s)
+}
+
+func convertOption(_ *JiraProject, val any, _ *string) ([]string, error) {
+	return []string{optionValue(val)}, nil
+}
+
+func convertOptions(_ *JiraProject, val any, _ *string) ([]string, error) {
+	items, _ := val.([]any)
+	values := []string{}
+	for _, item := range items {
+		if v := optionValue(item); v != "" {
+			values = append(values, v)
+		}
+	}
+	return []string{strings.Join(values, ", ")}, nil
+}
+
+// userName returns the name of a user picker value, linked if link_names is set
+func userName(project *JiraProject, val any) string {
+	m, ok := val.(map[string]any)
+	if !ok {
+		return ""
+	}
+	s, _ := m["displayName"].(string)
+	if s == "" {
+		return ""
+	}
+	s = PersonName(s)
+	if *project.Options.Outputs.Logseq.LinkNames {
+		s = "[[" + s + "]]"
+	}
+	return s
+}
+
+func convertUser(project *JiraProject, val any, _ *string) ([]string, error) {
+	return []string{userName(project, val)}, nil
+}
+
+func convertUsers(project *JiraProject, val any, _ *string) ([]string, error) {
+	items, _ := val.([]any)
+	names := []string{}
+	for _, item := range items {
+		if name := userName(project, item); name != "" {
+			names = append(names, name)
+		}
+	}
+	return []string{strings.Join(names, ", ")}, nil
+}
+
+func convertNumber(_ *JiraProject, val any, _ *string) ([]string, error) {
+	switch v := val.(type) {
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}, nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed in strconv.ParseFloat")
+		}
+		return []string{strconv.FormatFloat(f, 'f', -1, 64)}, nil
+	}
+	return nil, nil
+}
+
+func convertURL(_ *JiraProject, val any, _ *string) ([]string, error) {
+	s, _ := val.(string)
+	return []string{strings.TrimSpace(s)}, nil
+}
+
+func convertLabels(_ *JiraProject, val any, _ *string) ([]string, error) {
+	items, _ := val.([]any)
+	labels := []string{}
+	for _, item := range items {
+		if s, ok := item.(string); ok && s != "" {
+			labels = append(labels, "[["+LogseqTransform(s)+"]]")
+		}
+	}
+	return []string{strings.Join(labels, ", ")}, nil
+}
+
+// convertCascading joins the levels of a cascading select, {"value": "Parent", "child": {"value": "Child"}}
+func convertCascading(_ *JiraProject, val any, _ *string) ([]string, error) {
+	levels := []string{}
+	for val != nil {
+		v := optionValue(val)
+		if v == "" {
+			break
+		}
+		levels = append(levels, v)
+		val = val.(map[string]any)["child"]
+	}
+	return []string{strings.Join(levels, " > ")}, nil
+}
+
+// convertJSONPath picks a value out of a field by a dot separated path, e.g. "sprint.name" or "*.name" for arrays
+func convertJSONPath(_ *JiraProject, val any, path *string) ([]string, error) {
+	if path == nil || *path == "" {
+		return nil, errors.New("json-path needs a path")
+	}
+
+	found := gabs.Wrap(val).Path(*path).Data()
+
+	items, ok := found.([]any)
+	if !ok {
+		items = []any{found}
+	}
+
+	values := []string{}
+	for _, item := range items {
+		switch v := item.(type) {
+		case nil:
+		case string:
+			values = append(values, LogseqValue(v))
+		case float64:
+			values = append(values, strconv.FormatFloat(v, 'f', -1, 64))
+		default:
+			values = append(values, LogseqValue(fmt.Sprint(v)))
+		}
+	}
+	return []string{strings.Join(values, ", ")}, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCustomFieldConverters(t *testing.T) {
+
+	linked, unlinked := &JiraProject{}, &JiraProject{}
+	yes, no := true, false
+	linked.Options.Outputs.Logseq.LinkDates = &yes
+	linked.Options.Outputs.Logseq.LinkNames = &yes
+	unlinked.Options.Outputs.Logseq.LinkDates = &no
+	unlinked.Options.Outputs.Logseq.LinkNames = &no
+
+	path := func(s string) *string { return &s }
+
+	tests := []struct {
+		as       string
+		project  *JiraProject
+		value    string // JSON, as the field comes from Jira
+		path     *string
+		expected []Property
+	}{
+		{"date_sortable", linked, `"2024-05-10"`, nil, []Property{{"f", "[[May 10th, 2024]]"}, {"f-sortable", "20240510"}}},
+		{"date_sortable", unlinked, `"2024-05-10"`, nil, []Property{{"f-sortable", "20240510"}}},
+		{"date_sortable", linked, `"10/05/2024"`, nil, nil},
+		{"date_sortable", linked, `null`, nil, nil},
+		{"date_sortable", linked, `20240510`, nil, nil},
+
+		{"datetime", linked, `"2024-05-10T13:46:45.585-0500"`, nil, []Property{{"f", "[[May 10th, 2024]] 13:46"}, {"f-sortable", "20240510"}}},
+		{"datetime", unlinked, `"2024-05-10T13:46:45-0500"`, nil, []Property{{"f", "May 10th, 2024 13:46"}, {"f-sortable", "20240510"}}},
+		{"datetime", unlinked, `"2024-05-10T13:46:45Z"`, nil, []Property{{"f", "May 10th, 2024 13:46"}, {"f-sortable", "20240510"}}},
+		{"datetime", unlinked, `"2024-05-10T13:46:45.5+02:00"`, nil, []Property{{"f", "May 10th, 2024 13:46"}, {"f-sortable", "20240510"}}},
+		{"datetime", unlinked, `"yesterday"`, nil, nil},
+		{"datetime", unlinked, `null`, nil, nil},
+		{"datetime", unlinked, `1715348805`, nil, nil},
+
+		{"option", linked, `{"value": "Red", "id": "1"}`, nil, []Property{{"f", "Red"}}},
+		{"option", linked, `null`, nil, nil},
+		{"option", linked, `"Red"`, nil, nil},
+		{"option", linked, `{"id": "1"}`, nil, nil},
+
+		{"options", linked, `[{"value": "Red"}, {"value": "Blue"}]`, nil, []Property{{"f", "Red, Blue"}}},
+		{"options", linked, `[{"value": "Red"}, "Blue", null]`, nil, []Property{{"f", "Red"}}},
+		{"options", linked, `[]`, nil, nil},
+		{"options", linked, `null`, nil, nil},
+		{"options", linked, `{"value": "Red"}`, nil, nil},
+
+		{"user", linked, `{"displayName": "Jane Doe2"}`, nil, []Property{{"f", "[[Jane Doe]]"}}},
+		{"user", unlinked, `{"displayName": "Jane Doe"}`, nil, []Property{{"f", "Jane Doe"}}},
+		{"user", linked, `{"accountId": "1"}`, nil, nil},
+		{"user", linked, `null`, nil, nil},
+		{"user", linked, `"Jane Doe"`, nil, nil},
+
+		{"users", unlinked, `[{"displayName": "Jane Doe"}, {"displayName": "John Roe"}]`, nil, []Property{{"f", "Jane Doe, John Roe"}}},
+		{"users", linked, `[{"displayName": "Jane Doe"}, 3]`, nil, []Property{{"f", "[[Jane Doe]]"}}},
+		{"users", linked, `null`, nil, nil},
+		{"users", linked, `{"displayName": "Jane Doe"}`, nil, nil},
+
+		{"number", linked, `3`, nil, []Property{{"f", "3"}}},
+		{"number", linked, `0.5`, nil, []Property{{"f", "0.5"}}},
+		{"number", linked, `"2.50"`, nil, []Property{{"f", "2.5"}}},
+		{"number", linked, `"lots"`, nil, nil},
+		{"number", linked, `""`, nil, nil},
+		{"number", linked, `null`, nil, nil},
+		{"number", linked, `[3]`, nil, nil},
+
+		{"url", linked, `" https://example.com/a "`, nil, []Property{{"f", "https://example.com/a"}}},
+		{"url", linked, `null`, nil, nil},
+		{"url", linked, `42`, nil, nil},
+
+		{"labels", linked, `["backend", "ops/oncall", "[[Team]]"]`, nil, []Property{{"f", "[[backend]], [[ops／oncall]], [[( Team )]]"}}},
+		{"labels", linked, `["", 3, "ui"]`, nil, []Property{{"f", "[[ui]]"}}},
+		{"labels", linked, `null`, nil, nil},
+		{"labels", linked, `"backend"`, nil, nil},
+
+		{"cascading", linked, `{"value": "Parent", "child": {"value": "Child"}}`, nil, []Property{{"f", "Parent > Child"}}},
+		{"cascading", linked, `{"value": "Parent"}`, nil, []Property{{"f", "Parent"}}},
+		{"cascading", linked, `{"value": "Parent", "child": "Child"}`, nil, []Property{{"f", "Parent"}}},
+		{"cascading", linked, `null`, nil, nil},
+		{"cascading", linked, `["Parent"]`, nil, nil},
+
+		{"json-path", linked, `[{"name": "Sprint 1"}, {"name": "Sprint 2"}]`, path("*.name"), []Property{{"f", "Sprint 1, Sprint 2"}}},
+		{"json-path", linked, `{"sprint": {"name": "Sprint 1", "id": 7}}`, path("sprint.id"), []Property{{"f", "7"}}},
+		{"json-path", linked, `{"sprint": {"active": true}}`, path("sprint.active"), []Property{{"f", "true"}}},
+		{"json-path", linked, `{"sprint": {}}`, path("sprint.name"), nil},
+		{"json-path", linked, `null`, path("sprint.name"), nil},
+		{"json-path", linked, `"Sprint 1"`, path("sprint.name"), nil},
+		{"json-path", linked, `{"sprint": {"name": "Sprint 1"}}`, nil, nil},
+	}
+
+	for _, test := range tests {
+		var value any
+		if err := json.Unmarshal([]byte(test.value), &value); err != nil {
+			t.Fatal(err)
+		}
+		as := test.as
+		output, err := ConvertCustomField(test.project, "f", &as, test.path, value)
+		if err != nil {
+			t.Errorf("%s of %s failed: %v", test.as, test.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(output, test.expected) {
+			t.Errorf("%s of %s gave %q, expected %q", test.as, test.value, output, test.expected)
+		}
+	}
+}
+
+func TestCustomFieldUnconverted(t *testing.T) {
+
+	output, err := ConvertCustomField(&JiraProject{}, "f", nil, nil, "Some text")
+	if err != nil || !reflect.DeepEqual(output, []Property{{"f", "Some text"}}) {
+		t.Errorf("text gave %q, err %v", output, err)
+	}
+
+	output, err = ConvertCustomField(&JiraProject{}, "f", nil, nil, 3.0)
+	if err != nil || output != nil {
+		t.Errorf("a number without `as` gave %q, err %v", output, err)
+	}
+
+	unknown := "colour"
+	if _, err := ConvertCustomField(&JiraProject{}, "f", &unknown, nil, "Red"); err == nil {
+		t.Error("an unknown conversion didn't fail")
+	}
+}
@@ -0,0 +1,823 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// A small expression language for computed_fields.
+//
+// Values are null, booleans, numbers, strings, dates, lists and maps.
+// Operators are, loosest first, `? :`, `||`, `&&`, `== != < <= > >= in`, `+ -`, `* / %`, then unary `! -`.
+// Arithmetic on null gives null, so a field that isn't set leaves the result unset too.
+// Subtracting dates gives days, adding a number to a date adds days.
+// Fields of maps are reached with `.name` or `["name"]`, items of lists with `[0]`.
+
+type exprNode interface {
+	eval(env map[string]any) (any, error)
+}
+
+type (
+	exprLiteral struct{ value any }
+	exprIdent   struct{ name string }
+	exprList    struct{ items []exprNode }
+	exprIndex   struct{ target, index exprNode }
+	exprCall    struct {
+		name string
+		args []exprNode
+	}
+	exprUnary struct {
+		op      string
+		operand exprNode
+	}
+	exprBinary struct {
+		op          string
+		left, right exprNode
+	}
+	exprTernary struct{ cond, then, otherwise exprNode }
+)
+
+var (
+	exprCache     = map[string]exprNode{}
+	exprCacheLock = &sync.Mutex{}
+)
+
+// EvalExpr evaluates an expression with the given variables, parsing it on first use
+func EvalExpr(source string, env map[string]any) (any, error) {
+
+	exprCacheLock.Lock()
+	node, ok := exprCache[source]
+	exprCacheLock.Unlock()
+
+	if !ok {
+		var err error
+		node, err = ParseExpr(source)
+		if err != nil {
+			return nil, err
+		}
+		exprCacheLock.Lock()
+		exprCache[source] = node
+		exprCacheLock.Unlock()
+	}
+
+	return node.eval(env)
+}
+
+// ParseExpr parses an expression, reporting the position of any syntax error
+func ParseExpr(source string) (exprNode, error) {
+	tokens, err := lexExpr(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens, source: source}
+	node, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != "eof" {
+		return nil, p.errorAt(t, "unexpected `"+t.text+"`")
+	}
+	return node, nil
+}
+
+// Lexing
+
+type exprToken struct {
+	kind string // number, string, ident, op or eof
+	text string
+	pos  int
+}
+
+var exprOperators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "+", "-", "*", "/", "%", "!", "?", ":", "(", ")", "[", "]", ",", "."}
+
+func lexExpr(source string) (tokens []exprToken, err error) {
+
+	runes := []rune(source)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, exprToken{"number", string(runes[start:i]), start})
+
+		case r == '"' || r == '\'':
+			start := i
+			text := strings.Builder{}
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				text.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, errors.New("Unterminated string at " + strconv.Itoa(start) + " in `" + source + "`")
+			}
+			i++
+			tokens = append(tokens, exprToken{"string", text.String(), start})
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, exprToken{"ident", string(runes[start:i]), start})
+
+		default:
+			matched := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					tokens = append(tokens, exprToken{"op", op, i})
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, errors.New("Unexpected `" + string(r) + "` at " + strconv.Itoa(i) + " in `" + source + "`")
+			}
+		}
+	}
+
+	return append(tokens, exprToken{"eof", "end of expression", len(runes)}), nil
+}
+
+// Parsing, by recursive descent with one function per precedence level
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+	source string
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	t := p.tokens[p.pos]
+	if t.kind != "eof" {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	t := p.peek()
+	if (t.kind == "op" || t.kind == "ident") && slices.Contains(ops, t.text) {
+		p.pos++
+		return t.text, true
+	}
+	return "", false
+}
+
+func (p *exprParser) expect(op string) error {
+	if _, ok := p.accept(op); !ok {
+		return p.errorAt(p.peek(), "expected `"+op+"` but found `"+p.peek().text+"`")
+	}
+	return nil
+}
+
+func (p *exprParser) errorAt(t exprToken, message string) error {
+	return errors.New("Syntax error at " + strconv.Itoa(t.pos) + " in `" + p.source + "`: " + message)
+}
+
+func (p *exprParser) expression() (exprNode, error) {
+	cond, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.accept("?"); !ok {
+		return cond, nil
+	}
+	then, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	return &exprTernary{cond, then, otherwise}, nil
+}
+
+// Binary operators by precedence, loosest first
+var exprPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *exprParser) binary(level int) (exprNode, error) {
+	if level == len(exprPrecedence) {
+		return p.unary()
+	}
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(exprPrecedence[level]...)
+		if !ok {
+			return left, nil
+		}
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &exprBinary{op, left, right}
+	}
+}
+
+func (p *exprParser) unary() (exprNode, error) {
+	if op, ok := p.accept("!", "-"); ok {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &exprUnary{op, operand}, nil
+	}
+	return p.postfix()
+}
+
+func (p *exprParser) postfix() (exprNode, error) {
+	node, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.peek().text == "." && p.peek().kind == "op":
+			p.next()
+			t := p.next()
+			if t.kind != "ident" {
+				return nil, p.errorAt(t, "expected a field name after `.`")
+			}
+			node = &exprIndex{node, &exprLiteral{t.text}}
+		case p.peek().text == "[" && p.peek().kind == "op":
+			p.next()
+			index, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect("]"); err != nil {
+				return nil, err
+			}
+			node = &exprIndex{node, index}
+		default:
+			return node, nil
+		}
+	}
+}
+
+func (p *exprParser) primary() (exprNode, error) {
+	t := p.next()
+
+	switch t.kind {
+	case "number":
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, p.errorAt(t, "bad number `"+t.text+"`")
+		}
+		return &exprLiteral{f}, nil
+
+	case "string":
+		return &exprLiteral{t.text}, nil
+
+	case "ident":
+		switch t.text {
+		case "true":
+			return &exprLiteral{true}, nil
+		case "false":
+			return &exprLiteral{false}, nil
+		case "null":
+			return &exprLiteral{nil}, nil
+		}
+		if _, ok := p.accept("("); !ok {
+			return &exprIdent{t.text}, nil
+		}
+		if _, ok := exprFunctions[t.text]; !ok {
+			return nil, p.errorAt(t, "unknown function `"+t.text+"`")
+		}
+		args, err := p.items(")")
+		if err != nil {
+			return nil, err
+		}
+		return &exprCall{t.text, args}, nil
+
+	case "op":
+		switch t.text {
+		case "(":
+			node, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			return node, p.expect(")")
+		case "[":
+			items, err := p.items("]")
+			if err != nil {
+				return nil, err
+			}
+			return &exprList{items}, nil
+		}
+	}
+
+	return nil, p.errorAt(t, "unexpected `"+t.text+"`")
+}
+
+// items parses a comma separated list up to and including the closing token
+func (p *exprParser) items(closing string) (items []exprNode, err error) {
+	if _, ok := p.accept(closing); ok {
+		return nil, nil
+	}
+	for {
+		item, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if _, ok := p.accept(closing); ok {
+			return items, nil
+		}
+		if err = p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Evaluation
+
+func (n *exprLiteral) eval(map[string]any) (any, error) {
+	return n.value, nil
+}
+
+func (n *exprIdent) eval(env map[string]any) (any, error) {
+	v, ok := env[n.name]
+	if !ok {
+		return nil, errors.New("Unknown name `" + n.name + "`")
+	}
+	return v, nil
+}
+
+func (n *exprList) eval(env map[string]any) (any, error) {
+	list := []any{}
+	for _, item := range n.items {
+		v, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+func (n *exprIndex) eval(env map[string]any) (any, error) {
+	target, err := n.target.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch t := target.(type) {
+	case map[string]any:
+		return t[exprString(index)], nil
+	case []any:
+		i, ok := index.(float64)
+		if !ok || int(i) < 0 || int(i) >= len(t) {
+			return nil, nil
+		}
+		return t[int(i)], nil
+	}
+	return nil, nil
+}
+
+func (n *exprCall) eval(env map[string]any) (any, error) {
+	args := make([]any, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := exprFunctions[n.name](args)
+	return v, errors.Wrap(err, "In "+n.name+"()")
+}
+
+func (n *exprUnary) eval(env map[string]any) (any, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		return !exprTruthy(v), nil
+	}
+	if v == nil {
+		return nil, nil
+	}
+	f, err := exprNumber(v)
+	return -f, err
+}
+
+func (n *exprTernary) eval(env map[string]any) (any, error) {
+	cond, err := n.cond.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if exprTruthy(cond) {
+		return n.then.eval(env)
+	}
+	return n.otherwise.eval(env)
+}
+
+func (n *exprBinary) eval(env map[string]any) (any, error) {
+
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op { // Short circuit
+	case "&&":
+		if !exprTruthy(left) {
+			return false, nil
+		}
+	case "||":
+		if exprTruthy(left) {
+			return true, nil
+		}
+	}
+
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "&&", "||":
+		return exprTruthy(right), nil
+	case "==":
+		return exprEqual(left, right), nil
+	case "!=":
+		return !exprEqual(left, right), nil
+	case "in":
+		list, ok := right.([]any)
+		if !ok {
+			return exprContains(right, left), nil
+		}
+		return slices.ContainsFunc(list, func(item any) bool { return exprEqual(item, left) }), nil
+	case "<", "<=", ">", ">=":
+		c, ok := exprCompare(left, right)
+		if !ok {
+			return false, nil
+		}
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		}
+		return c >= 0, nil
+	}
+
+	if left == nil || right == nil { // Arithmetic on null is null
+		return nil, nil
+	}
+
+	lt, lIsTime := left.(time.Time)
+	rt, rIsTime := right.(time.Time)
+
+	switch {
+	case n.op == "-" && lIsTime && rIsTime:
+		return float64(exprDay(lt).Sub(exprDay(rt)) / (24 * time.Hour)), nil
+	case (n.op == "+" || n.op == "-") && lIsTime:
+		days, err := exprNumber(right)
+		if err != nil {
+			return nil, err
+		}
+		if n.op == "-" {
+			days = -days
+		}
+		return lt.AddDate(0, 0, int(days)), nil
+	case n.op == "+":
+		_, lIsString := left.(string)
+		_, rIsString := right.(string)
+		if lIsString || rIsString {
+			return exprString(left) + exprString(right), nil
+		}
+	}
+
+	l, err := exprNumber(left)
+	if err != nil {
+		return nil, err
+	}
+	r, err := exprNumber(right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return nil, nil
+		}
+		return l / r, nil
+	}
+	if r == 0 {
+		return nil, nil
+	}
+	return math.Mod(l, r), nil
+}
+
+// Values
+
+// exprDay drops the time of day, so dates subtract to whole days wherever they were recorded
+func exprDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func exprTruthy(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case []any:
+		return len(v) > 0
+	case map[string]any:
+		return len(v) > 0
+	}
+	return true
+}
+
+func exprNumber(v any) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, errors.New("`" + v + "` is not a number")
+		}
+		return f, nil
+	}
+	return 0, errors.Errorf("%v is not a number", v)
+}
+
+func exprString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return DateFormat(v)
+	case []any:
+		items := []string{}
+		for _, item := range v {
+			items = append(items, exprString(item))
+		}
+		return strings.Join(items, ", ")
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func exprEqual(a, b any) bool {
+	if c, ok := exprCompare(a, b); ok {
+		return c == 0
+	}
+	return a == nil && b == nil
+}
+
+// exprCompare orders two values of the same kind, numbers also compare with numeric strings
+func exprCompare(a, b any) (int, bool) {
+	switch a := a.(type) {
+	case float64:
+		if f, err := exprNumber(b); err == nil {
+			switch {
+			case a < f:
+				return -1, true
+			case a > f:
+				return 1, true
+			}
+			return 0, true
+		}
+	case string:
+		switch b := b.(type) {
+		case string:
+			return strings.Compare(a, b), true
+		case float64:
+			c, ok := exprCompare(b, a)
+			return -c, ok
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			if a == b {
+				return 0, true
+			}
+			if b {
+				return -1, true
+			}
+			return 1, true
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			return exprDay(a).Compare(exprDay(b)), true
+		}
+	}
+	return 0, false
+}
+
+func exprContains(haystack, needle any) bool {
+	switch h := haystack.(type) {
+	case string:
+		return strings.Contains(h, exprString(needle))
+	case []any:
+		return slices.ContainsFunc(h, func(item any) bool { return exprEqual(item, needle) })
+	case map[string]any:
+		_, ok := h[exprString(needle)]
+		return ok
+	}
+	return false
+}
+
+// ParseExprDate reads the date formats Jira and this tool use
+func ParseExprDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"2006-01-02", "20060102", "2006-01-02T15:04:05.000-0700", time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("`" + s + "` is not a date")
+}
+
+// Functions
+
+func exprArgs(args []any, min, max int) error {
+	if len(args) < min || (max >= 0 && len(args) > max) {
+		return errors.New("Wrong number of arguments, got " + strconv.Itoa(len(args)))
+	}
+	return nil
+}
+
+func exprMath(f func(float64) float64) func([]any) (any, error) {
+	return func(args []any) (any, error) {
+		if err := exprArgs(args, 1, 1); err != nil || args[0] == nil {
+			return nil, err
+		}
+		n, err := exprNumber(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return f(n), nil
+	}
+}
+
+func exprExtreme(keep int) func([]any) (any, error) {
+	return func(args []any) (best any, err error) {
+		if err = exprArgs(args, 1, -1); err != nil {
+			return nil, err
+		}
+		if list, ok := args[0].([]any); ok && len(args) == 1 {
+			args = list
+		}
+		for _, v := range args {
+			if v == nil {
+				continue
+			}
+			if c, ok := exprCompare(v, best); best == nil || (ok && c == keep) {
+				best = v
+			}
+		}
+		return best, nil
+	}
+}
+
+var exprFunctions = map[string]func(args []any) (any, error){
+	"round": exprMath(math.Round),
+	"floor": exprMath(math.Floor),
+	"ceil":  exprMath(math.Ceil),
+	"abs":   exprMath(math.Abs),
+	"min":   exprExtreme(-1),
+	"max":   exprExtreme(1),
+	"number": func(args []any) (any, error) {
+		if err := exprArgs(args, 1, 1); err != nil || args[0] == nil {
+			return nil, err
+		}
+		return exprNumber(args[0])
+	},
+	"string": func(args []any) (any, error) {
+		if err := exprArgs(args, 1, 1); err != nil {
+			return nil, err
+		}
+		return exprString(args[0]), nil
+	},
+	"date": func(args []any) (any, error) { // Parses "2006-01-02", "20060102" or a Jira timestamp
+		if err := exprArgs(args, 1, 1); err != nil || args[0] == nil {
+			return nil, err
+		}
+		if t, ok := args[0].(time.Time); ok {
+			return t, nil
+		}
+		return ParseExprDate(exprString(args[0]))
+	},
+	"sortable": func(args []any) (any, error) { // 20060102, for comparing against query inputs
+		if err := exprArgs(args, 1, 1); err != nil || args[0] == nil {
+			return nil, err
+		}
+		t, ok := args[0].(time.Time)
+		if !ok {
+			return nil, errors.New("Not a date")
+		}
+		return t.Format("20060102"), nil
+	},
+	"len": func(args []any) (any, error) {
+		if err := exprArgs(args, 1, 1); err != nil {
+			return nil, err
+		}
+		switch v := args[0].(type) {
+		case string:
+			return float64(len([]rune(v))), nil
+		case []any:
+			return float64(len(v)), nil
+		case map[string]any:
+			return float64(len(v)), nil
+		}
+		return float64(0), nil
+	},
+	"lower": func(args []any) (any, error) {
+		if err := exprArgs(args, 1, 1); err != nil {
+			return nil, err
+		}
+		return strings.ToLower(exprString(args[0])), nil
+	},
+	"upper": func(args []any) (any, error) {
+		if err := exprArgs(args, 1, 1); err != nil {
+			return nil, err
+		}
+		return strings.ToUpper(exprString(args[0])), nil
+	},
+	"contains": func(args []any) (any, error) {
+		if err := exprArgs(args, 2, 2); err != nil {
+			return nil, err
+		}
+		return exprContains(args[0], args[1]), nil
+	},
+	"default": func(args []any) (any, error) { // First argument that is set
+		for _, v := range args {
+			if v != nil && v != "" {
+				return v, nil
+			}
+		}
+		return nil, nil
+	},
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvalExpr(t *testing.T) {
+
+	date := func(s string) time.Time {
+		d, err := ParseExprDate(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	env := map[string]any{
+		"today":        date("2024-03-20"),
+		"date_due":     date("2024-03-15"),
+		"created":      date("2024-01-02T09:30:00.000+0000"),
+		"story_points": 3.0,
+		"unset":        nil,
+		"status":       "In Progress",
+		"labels":       []any{"backend", "urgent"},
+		"fields":       map[string]any{"customfield_1": map[string]any{"value": "High"}},
+	}
+
+	tests := []struct {
+		expr     string
+		expected any
+	}{
+		// Precedence
+		{"1 + 2 * 3", 7.0},
+		{"(1 + 2) * 3", 9.0},
+		{"10 - 4 - 3", 3.0},
+		{"-2 * 3", -6.0},
+		{"7 % 4 + 1", 4.0},
+		{"1 + 2 == 3 && 2 < 1 || true", true},
+		{"!false && false", false},
+		{"1 < 2 ? 'yes' : 'no'", "yes"},
+		{"false ? 1 : true ? 2 : 3", 2.0},
+		{"story_points <= 2 ? 'S' : story_points <= 5 ? 'M' : 'L'", "M"},
+
+		// Null propagation
+		{"unset + 1", nil},
+		{"unset * story_points", nil},
+		{"-unset", nil},
+		{"round(unset)", nil},
+		{"unset == null", true},
+		{"unset != null", false},
+		{"unset > 1", false},
+		{"default(unset, 'fallback')", "fallback"},
+		{"1 / 0", nil},
+
+		// Dates
+		{"today - date_due", 5.0},
+		{"date_due - today", -5.0},
+		{"today - created", 78.0}, // Time of day is ignored
+		{"date_due + 7", date("2024-03-22")},
+		{"date_due - 15", date("2024-02-29")},
+		{"date_due < today", true},
+		{"date('20240315') == date_due", true},
+		{"sortable(date_due)", "20240315"},
+		{"max(0, today - date_due)", 5.0},
+
+		// Strings, lists and maps
+		{"'story: ' + story_points", "story: 3"},
+		{"'urgent' in labels", true},
+		{"'Progress' in status", true},
+		{"labels[1]", "urgent"},
+		{"labels[5]", nil},
+		{"fields.customfield_1.value", "High"},
+		{`fields["customfield_1"]["value"]`, "High"},
+		{"len(labels)", 2.0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			v, err := EvalExpr(test.expr, env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(v, test.expected) {
+				t.Errorf("got %#v, expected %#v", v, test.expected)
+			}
+		})
+	}
+}
+
+func TestEvalExprErrors(t *testing.T) {
+
+	env := map[string]any{"status": "Done"}
+
+	tests := []struct {
+		expr  string
+		error string // Part of the message expected
+	}{
+		{"1 +", "Syntax error at 3"},
+		{"(1 + 2", "expected `)`"},
+		{"1 2", "unexpected `2`"},
+		{"'open", "Unterminated string at 0"},
+		{"1 # 2", "Unexpected `#` at 2"},
+		{"nope(1)", "unknown function `nope`"},
+		{"a ? 1", "expected `:`"},
+		{"fields.", "expected a field name"},
+		{"missing + 1", "Unknown name `missing`"},
+		{"status * 2", "`Done` is not a number"},
+		{"round(1, 2)", "Wrong number of arguments"},
+		{"sortable('x')", "Not a date"},
+		{"date('soon')", "`soon` is not a date"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			_, err := EvalExpr(test.expr, env)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), test.error) {
+				t.Errorf("got %q, expected it to contain %q", err.Error(), test.error)
+			}
+		})
+	}
+}
+
+func TestExprValue(t *testing.T) {
+
+	tests := []struct {
+		key      string
+		value    string
+		expected any
+	}{
+		{"story-points", "5", 5.0},
+		{"team", "Platform", "Platform"},
+		{"date-start-sortable", "20240102", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"date-start-sortable", "unknown", "unknown"},
+		{"date-start", "[[Jan 2nd, 2024]]", "[[Jan 2nd, 2024]]"},
+	}
+
+	for _, test := range tests {
+		if v := exprValue(test.key, test.value); !reflect.DeepEqual(v, test.expected) {
+			t.Errorf("exprValue(%q, %q) = %#v, expected %#v", test.key, test.value, v, test.expected)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/MagicalTux/natsort"
+	jira "github.com/andygrunwald/go-jira/v2/cloud"
+	"github.com/pkg/errors"
+)
+
+// Matches field IDs that are used as-is in custom_fields, without looking them up
+var customFieldIDMatcher = regexp.MustCompile(`^customfield_[0-9]+$`)
+
+// Connect layers the instance's options and creates its client, the client is left nil if the instance is disabled
+func (c *JiraConfig) Connect() (err error) {
+
+	instanceOptions, err := UnderlayOptions(&config.Jira.Options, &c.Options)
+	if err != nil {
+		return errors.Wrap(err, "Couldn't merge GeneralOptions with InstanceOptions")
+	}
+	c.Options = *instanceOptions
+
+	for _, p := range c.Projects {
+		p.config = c
+	}
+
+	if !*c.Options.Enabled {
+		return nil
+	}
+
+	c.limiter = NewRateLimiter(c)
+
+	c.client, err = c.createClient()
+	if err != nil {
+		return errors.Wrap(err, "Couldn't create a client")
+	}
+
+	return nil
+}
+
+// GetFields returns every field of an instance, system and custom
+func GetFields(c *JiraConfig) ([]jira.Field, error) {
+
+	o, _, err := APIWrapper(c, func(a []any) (output []any, resp *jira.Response, err error) {
+		output = make([]any, 1)
+
+		req, err := c.client.NewRequest(context.Background(), http.MethodGet, "rest/api/3/field", nil)
+		if err != nil {
+			return output, nil, errors.Wrap(err, "Error in c.client.NewRequest")
+		}
+
+		fields := []jira.Field{}
+		resp, err = c.client.Do(req, &fields)
+		output[0] = fields
+		return output, resp, errors.Wrap(err, "Couldn't get fields")
+	}, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed in APIWrapper getting fields")
+	}
+	if o == nil {
+		return nil, errors.New("No fields found on " + *c.Connection.BaseURL)
+	}
+
+	fields := o[0].([]jira.Field)
+
+	slices.SortFunc(fields, func(a, b jira.Field) int {
+		if natsort.Compare(a.ID, b.ID) {
+			return -1
+		}
+		return 1
+	})
+
+	return fields, nil
+}
+
+// FieldType describes a field's schema, e.g. "number" or "array of option"
+func FieldType(f jira.Field) string {
+	if f.Schema.Type == "array" && f.Schema.Items != "" {
+		return "array of " + f.Schema.Items
+	}
+	return f.Schema.Type
+}
+
+// ResolveFieldNames replaces custom_fields given by name with their IDs on this instance.
+// Names must match exactly one field, ignoring case.
+func ResolveFieldNames(c *JiraConfig) error {
+
+	var fields []jira.Field
+
+	resolve := func(options *JiraOptions, where string) (err error) {
+		options.CustomFields = slices.Clone(options.CustomFields) // Entries may be shared with the options they were layered from
+
+		for i, customField := range options.CustomFields {
+			if customField.From == nil || customFieldIDMatcher.MatchString(*customField.From) {
+				continue
+			}
+
+			if fields == nil {
+				fields, err = GetFields(c)
+				if err != nil {
+					return err
+				}
+			}
+
+			matches := []jira.Field{}
+			for _, f := range fields {
+				if f.ID == *customField.From {
+					matches = []jira.Field{f}
+					break
+				}
+				if strings.EqualFold(f.Name, *customField.From) {
+					matches = append(matches, f)
+				}
+			}
+
+			switch len(matches) {
+			case 0:
+				return errors.New("No field named `" + *customField.From + "` in the custom_fields of " + where + ", run `logseq-tools fields` to list them")
+			case 1:
+				id := matches[0].ID
+				options.CustomFields[i].From = &id
+			default:
+				ids := []string{}
+				for _, f := range matches {
+					ids = append(ids, f.ID)
+				}
+				return errors.New("Field name `" + *customField.From + "` in the custom_fields of " + where + " is ambiguous, use one of " + strings.Join(ids, ", ") + " instead")
+			}
+		}
+		return nil
+	}
+
+	err := resolve(&c.Options, *c.Connection.BaseURL)
+	if err != nil {
+		return err
+	}
+
+	for _, project := range c.Projects {
+		err = resolve(&project.Options, *c.Connection.BaseURL+" project "+*project.Key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListFields prints every field of every enabled instance, for the `fields` subcommand
+func ListFields() error {
+
+	for _, c := range config.Jira.Instances {
+		err := c.Connect()
+		if err != nil {
+			return errors.Wrap(err, "Failed to connect to "+*c.Connection.BaseURL)
+		}
+		if c.client == nil { // Disabled
+			continue
+		}
+
+		fields, err := GetFields(c)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(*c.Connection.BaseURL)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tName\tType\tCustom")
+		for _, f := range fields {
+			custom := "no"
+			if f.Custom {
+				custom = "yes"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.ID, f.Name, FieldType(f), custom)
+		}
+
+		err = w.Flush()
+		if err != nil {
+			return errors.Wrap(err, "Failed to print fields")
+		}
+
+		fmt.Println()
+	}
+
+	return nil
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira/v2/cloud"
+	"github.com/pkg/errors"
+)
+
+// IssueUrlMatcher matches URLs to an instance's issues in descriptions and comments, capturing the key
+type IssueUrlMatcher struct {
+	instance *JiraConfig
+	matcher  *regexp.Regexp
+}
+
+// IssueID identifies an issue across every configured instance, it is the issue's browse URL.
+// knownIssues, parents and children are all keyed by it, as keys alone collide between instances.
+func IssueID(c *JiraConfig, key string) string {
+	return *c.Connection.BaseURL + "browse/" + key
+}
+
+// SplitIssueID returns the base URL and key an IssueID was made from
+func SplitIssueID(id string) (baseURL string, key string) {
+	i := strings.LastIndex(id, "browse/")
+	if i < 0 {
+		return "", id
+	}
+	return id[:i], id[i+len("browse/"):]
+}
+
+// InstanceOf returns the configured instance an IssueID belongs to, if any
+func InstanceOf(id string) *JiraConfig {
+	baseURL, _ := SplitIssueID(id)
+	for _, c := range config.Jira.Instances {
+		if *c.Connection.BaseURL == baseURL {
+			return c
+		}
+	}
+	return nil
+}
+
+// InstanceOfSelf returns the configured instance an issue's self link points at, if any
+func InstanceOfSelf(self string) *JiraConfig {
+	i := strings.Index(self, "rest/api/")
+	if i < 0 {
+		return nil
+	}
+	for _, c := range config.Jira.Instances {
+		if *c.Connection.BaseURL == self[:i] {
+			return c
+		}
+	}
+	return nil
+}
+
+// ProjectOf returns the configured project a known issue belongs to, if any
+func ProjectOf(id string, issue *jira.Issue) *JiraProject {
+	c := InstanceOf(id)
+	if c == nil || issue.Fields == nil {
+		return nil
+	}
+	for _, p := range c.Projects {
+		if *p.Key == issue.Fields.Project.Key {
+			return p
+		}
+	}
+	return nil
+}
+
+// InstanceDir returns the directory separating an instance's cache and attachments from the others.
+// It is empty with a single instance, so existing caches and assets stay where they are.
+func InstanceDir(c *JiraConfig) string {
+	if len(config.Jira.Instances) < 2 {
+		return ""
+	}
+	u, err := url.Parse(*c.Connection.BaseURL)
+	if err != nil || u.Host == "" {
+		return InstanceName(c)
+	}
+	return u.Host
+}
+
+// TaskID returns the stable block UUID of an issue's task.
+// The first instance hashes the key alone, as every instance did before there could be more than one, so adding
+// an instance leaves its task IDs, and references to them, as they were. Other instances hash the IssueID.
+func TaskID(c *JiraConfig, key string) string {
+	if len(config.Jira.Instances) < 2 || c == config.Jira.Instances[0] {
+		return deterministicGUID(key)
+	}
+	return deterministicGUID(IssueID(c, key))
+}
+
+// PageCollision returns an instance configured before a project's that has a project of the same key and the same
+// instance_prefix, so its pages would be overwritten by the project's, or nil if there is none
+func PageCollision(project *JiraProject) *JiraConfig {
+	for _, other := range config.Jira.Instances {
+		if other == project.config {
+			return nil
+		}
+		if PageKey(other, "") != PageKey(project.config, "") {
+			continue
+		}
+		for _, p := range other.Projects {
+			if *p.Key == *project.Key {
+				return other
+			}
+		}
+	}
+	return nil
+}
+
+// MigrateInstanceDirs moves a cache and attachments written with a single instance to where that instance's go,
+// once more instances are configured. The instance is found from each cached issue's self link, as for known issues.
+// It returns whether anything was moved, as pages linking the moved attachments have to be written again.
+func MigrateInstanceDirs() (moved bool, err error) {
+
+	if len(config.Jira.Instances) < 2 {
+		return false, nil
+	}
+
+	if _, ok := sink.(*FSSink); !ok { // Other sinks hold this run's output alone, there's nothing in them to move
+		slog.Info("Not moving the cache and attachments into per instance directories, as output isn't going to the filesystem")
+		return false, nil
+	}
+
+	cacheRoot := *config.Jira.Options.Paths.CacheRoot
+	assetsDir := *config.Jira.Options.Outputs.Logseq.LogseqRoot + "/assets/jira"
+
+	instanceDirs := map[string]bool{}
+	for _, c := range config.Jira.Instances {
+		instanceDirs[InstanceDir(c)] = true
+	}
+
+	entries, err := os.ReadDir(cacheRoot)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to read cache directory "+cacheRoot)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || instanceDirs[entry.Name()] { // Issues are cached in a directory per key
+			continue
+		}
+
+		legacyDir := cacheRoot + "/" + entry.Name()
+
+		files, err := os.ReadDir(legacyDir)
+		if err != nil {
+			return moved, errors.Wrap(err, "Failed to read cache directory "+legacyDir)
+		}
+
+		var instance *JiraConfig
+		var attachments []*jira.Attachment
+
+		for _, f := range files {
+			if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") || strings.HasSuffix(f.Name(), "_watchers.json") {
+				continue
+			}
+			raw, err := ReadCacheFile(legacyDir + "/" + f.Name())
+			if err != nil {
+				return moved, errors.Wrap(err, "Failed to read cached issue "+f.Name())
+			}
+			issue := &jira.Issue{}
+			if json.Unmarshal(raw, issue) != nil {
+				continue
+			}
+			if c := InstanceOfSelf(issue.Self); c != nil {
+				instance = c
+			}
+			if issue.Fields != nil {
+				attachments = append(attachments, issue.Fields.Attachments...)
+			}
+		}
+
+		if instance == nil {
+			if len(files) > 0 {
+				slog.Warn("Leaving cache directory " + legacyDir + ", couldn't tell which instance it is from")
+			}
+			continue
+		}
+
+		dir := InstanceDir(instance)
+
+		for _, f := range files {
+			if f.IsDir() {
+				continue
+			}
+			err = moveFile(legacyDir+"/"+f.Name(), cacheRoot+"/"+dir+"/"+entry.Name()+"/"+f.Name())
+			if err != nil {
+				return moved, err
+			}
+			moved = true
+		}
+
+		for _, a := range attachments {
+			if a == nil {
+				continue
+			}
+			from := assetsDir + "/jira_" + a.ID + filepath.Ext(a.Filename)
+			exists, err := FileExists(from)
+			if err != nil {
+				return moved, errors.Wrap(err, "Failed to check for attachment file "+from)
+			}
+			if !exists {
+				continue
+			}
+			err = moveFile(from, assetsDir+"/jira_"+dir+"_"+a.ID+filepath.Ext(a.Filename))
+			if err != nil {
+				return moved, err
+			}
+			moved = true
+		}
+	}
+
+	return moved, nil
+}
+
+// moveFile moves a file through the sink, leaving one already at the destination alone
+func moveFile(from string, to string) error {
+
+	exists, err := FileExists(to)
+	if err != nil {
+		return errors.Wrap(err, "Failed to check for file "+to)
+	}
+
+	if !exists {
+		contents, err := ReadFile(from)
+		if err != nil {
+			return errors.Wrap(err, "Failed to read file "+from)
+		}
+		err = WriteFile(to, contents)
+		if err != nil {
+			return errors.Wrap(err, "Failed to write file "+to)
+		}
+	}
+
+	return errors.Wrap(RemoveFile(from), "Failed to remove file "+from)
+}
+
+// PageKey returns the name an issue key goes by in page names, aliases and links
+func PageKey(c *JiraConfig, key string) string {
+	if c == nil || c.Options.Outputs.Logseq.InstancePrefix == nil {
+		return key
+	}
+	return *c.Options.Outputs.Logseq.InstancePrefix + key
+}
+
+// NormaliseBaseURLs makes sure every base URL ends in a slash, so IssueIDs match however it was configured
+func NormaliseBaseURLs() {
+	for _, c := range config.Jira.Instances {
+		if c.Connection.BaseURL != nil && !strings.HasSuffix(*c.Connection.BaseURL, "/") {
+			baseURL := *c.Connection.BaseURL + "/"
+			c.Connection.BaseURL = &baseURL
+		}
+	}
+}
+
+// MigrateKnownIssues rekeys a cache of known issues written by key alone.
+// The instance is found from the issue's self link, issues that can't be placed are dropped and fetched again.
+func MigrateKnownIssues(issues map[string]*jira.Issue) map[string]*jira.Issue {
+
+	migrated := map[string]*jira.Issue{}
+
+	for k, issue := range issues {
+		if strings.Contains(k, "://") {
+			migrated[k] = issue
+			continue
+		}
+
+		instance := InstanceOfSelf(issue.Self)
+
+		if instance == nil && len(config.Jira.Instances) == 1 {
+			instance = config.Jira.Instances[0]
+		}
+
+		if instance == nil {
+			slog.Warn("Dropping cached issue " + k + ", couldn't tell which instance it is from")
+			continue
+		}
+
+		migrated[IssueID(instance, issue.Key)] = issue
+	}
+
+	return migrated
+}
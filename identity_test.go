@@ -0,0 +1,161 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testInstance(baseURL string) *JiraConfig {
+	c := &JiraConfig{}
+	c.Connection.BaseURL = &baseURL
+	return c
+}
+
+// testInstances configures instances for a test, restoring the config after
+func testInstances(t *testing.T, instances ...*JiraConfig) {
+	t.Helper()
+	previous := config.Jira
+	t.Cleanup(func() { config.Jira = previous })
+	config.Jira.Instances = instances
+}
+
+func TestTaskID(t *testing.T) {
+
+	acme := testInstance("https://acme.atlassian.net/")
+	other := testInstance("https://other.atlassian.net/")
+
+	testInstances(t, acme)
+	if TaskID(acme, "PROJ-1") != deterministicGUID("PROJ-1") {
+		t.Error("task IDs changed with a single instance")
+	}
+
+	testInstances(t, acme, other)
+	if TaskID(acme, "PROJ-1") != deterministicGUID("PROJ-1") {
+		t.Error("task IDs of the first instance changed when another was added")
+	}
+	if TaskID(acme, "PROJ-1") == TaskID(other, "PROJ-1") {
+		t.Error("task IDs collide between instances")
+	}
+}
+
+func TestPageCollision(t *testing.T) {
+
+	project := func(c *JiraConfig, key string) *JiraProject {
+		p := &JiraProject{Key: &key, config: c}
+		c.Projects = append(c.Projects, p)
+		return p
+	}
+
+	acme := testInstance("https://acme.atlassian.net/")
+	other := testInstance("https://other.atlassian.net/")
+	prefixed := testInstance("https://prefixed.atlassian.net/")
+	prefix := "p-"
+	prefixed.Options.Outputs.Logseq.InstancePrefix = &prefix
+	testInstances(t, acme, other, prefixed)
+
+	acmeOps := project(acme, "OPS")
+	otherOps := project(other, "OPS")
+	otherWeb := project(other, "WEB")
+	prefixedOps := project(prefixed, "OPS")
+
+	if PageCollision(acmeOps) != nil {
+		t.Error("the first instance's project was skipped")
+	}
+	if PageCollision(otherOps) != acme {
+		t.Error("a project with the same key and prefix wasn't caught")
+	}
+	if PageCollision(otherWeb) != nil || PageCollision(prefixedOps) != nil {
+		t.Error("a project with its own key or prefix was caught")
+	}
+}
+
+func TestMigrateInstanceDirs(t *testing.T) {
+
+	root := t.TempDir()
+	cacheRoot := filepath.Join(root, "cache")
+	logseqRoot := filepath.Join(root, "notes")
+
+	write := func(path string, contents string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A cache and attachment written with only acme configured
+	write(filepath.Join(cacheRoot, "PROJ-1", "2024-01-02T10-00-00Z.json"), `{
+		"self": "https://acme.atlassian.net/rest/api/2/issue/10001",
+		"key": "PROJ-1",
+		"fields": {"attachment": [{"id": "20001", "filename": "log.txt"}]}
+	}`)
+	write(filepath.Join(cacheRoot, "PROJ-1", "2024-01-02T10-00-00Z_watchers.json"), `[]`)
+	write(filepath.Join(cacheRoot, "knownIssues.json"), `{}`)
+	write(filepath.Join(logseqRoot, "assets", "jira", "jira_20001.txt"), "log")
+
+	acme := testInstance("https://acme.atlassian.net/")
+	other := testInstance("https://other.atlassian.net/")
+	testInstances(t, acme)
+	config.Jira.Options.Paths.CacheRoot = &cacheRoot
+	config.Jira.Options.Outputs.Logseq.LogseqRoot = &logseqRoot
+
+	moved, err := MigrateInstanceDirs()
+	if err != nil || moved {
+		t.Fatalf("migrated with a single instance, moved %v, err %v", moved, err)
+	}
+
+	config.Jira.Instances = append(config.Jira.Instances, other)
+
+	moved, err = MigrateInstanceDirs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !moved {
+		t.Fatal("nothing was migrated")
+	}
+
+	for _, path := range []string{
+		filepath.Join(cacheRoot, "acme.atlassian.net", "PROJ-1", "2024-01-02T10-00-00Z.json"),
+		filepath.Join(cacheRoot, "acme.atlassian.net", "PROJ-1", "2024-01-02T10-00-00Z_watchers.json"),
+		filepath.Join(cacheRoot, "knownIssues.json"),
+		filepath.Join(logseqRoot, "assets", "jira", "jira_acme.atlassian.net_20001.txt"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s: %v", path, err)
+		}
+	}
+	for _, path := range []string{
+		filepath.Join(cacheRoot, "PROJ-1", "2024-01-02T10-00-00Z.json"),
+		filepath.Join(logseqRoot, "assets", "jira", "jira_20001.txt"),
+	} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be moved", path)
+		}
+	}
+
+	moved, err = MigrateInstanceDirs()
+	if err != nil || moved {
+		t.Errorf("migrated twice, moved %v, err %v", moved, err)
+	}
+}
+
+func TestMigrateInstanceDirsOtherSinks(t *testing.T) {
+
+	previous := sink
+	t.Cleanup(func() { sink = previous })
+	sink = NewMemorySink()
+
+	cacheRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(cacheRoot, "PROJ-1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	testInstances(t, testInstance("https://acme.atlassian.net/"), testInstance("https://other.atlassian.net/"))
+	config.Jira.Options.Paths.CacheRoot = &cacheRoot
+
+	moved, err := MigrateInstanceDirs()
+	if err != nil || moved {
+		t.Errorf("migrated into a memory sink, moved %v, err %v", moved, err)
+	}
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 	"os"
@@ -29,25 +28,31 @@ import (
 	"github.com/vbauerster/mpb/v8"
 	"github.com/vbauerster/mpb/v8/decor"
 	"golang.org/x/sync/errgroup"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
 )
 
 type JiraConfig struct {
 	Connection struct {
 		BaseURL     *string `json:"base_url"`
+		Name        *string `json:"name"` // Short name for {instance} in namespaces, defaults to the first part of the host name
 		Username    *string `json:"username"`
 		DisplayName *string `json:"display_name"`
 		APIToken    *string `json:"api_token"`
 		Parallel    *int    `json:"parallel"`
+		RateLimit   struct {
+			RequestsPerSecond *float64 `json:"requests_per_second"` // Steady-state request budget, 0 to disable
+			Burst             *int     `json:"burst"`               // Requests allowed in a burst above the steady-state budget
+			MaxRetries        *int     `json:"max_retries"`         // Retries for throttled, 5xx and network failures
+			BackoffBaseMs     *int     `json:"backoff_base_ms"`     // First backoff delay, doubled on each retry
+			BackoffMaxMs      *int     `json:"backoff_max_ms"`      // Upper bound on a single backoff delay
+		} `json:"rate_limit"`
 	} `json:"connection"`
 
 	Options  JiraOptions    `json:"options"`
 	Projects []*JiraProject `json:"projects"`
 
-	apiLimited *sync.Mutex  // Lock this to prevent calls while API cools down, unlock once done
-	client     *jira.Client // Client to use for communication
-	progress   map[string]*mpb.Bar
+	limiter  *RateLimiter // Shared by every call to this instance
+	client   *jira.Client // Client to use for communication
+	progress map[string]*mpb.Bar
 }
 
 type JiraProject struct {
@@ -59,6 +64,8 @@ type JiraProject struct {
 type JiraOptions struct {
 	Enabled *bool `json:"enabled"` // Whether to process this Jira project
 
+	Template *string `json:"template"` // Path to a text/template for issue pages, the built-in default is used if unset
+
 	Paths struct {
 		CacheRoot *string `json:"cache_root"`
 	} `json:"paths"`
@@ -76,8 +83,27 @@ type JiraOptions struct {
 			LinkDates        *bool   `json:"link_dates"`         // Whether to [[link]] dates
 			SearchUsers      *bool   `json:"search_users"`       // Whether to search users - may not be possible due to permissions
 			LogseqRoot       *string `json:"logseq_root"`
+			Format           *string `json:"format"`          // Either "markdown" or "org", to match the graph's :preferred-format
+			Namespace        *string `json:"namespace"`       // Namespace for issue pages, e.g. "work/{instance}/{project}", empty for none
+			HierarchyPage    *string `json:"hierarchy_page"`  // Name of the page listing every issue by parent
+			InstancePrefix   *string `json:"instance_prefix"` // Prepended to issue keys in page names, aliases and links, e.g. "acme-" for [[acme-OPS-123]]
+			WideTables       struct {
+				Columns *int    `json:"columns"` // Tables in descriptions and comments with more columns than this are wide
+				As      *string `json:"as"`      // "markdown" to keep wide tables as Markdown tables, or "query" for a block per row shown by a table query
+			} `json:"wide_tables"`
 		} `json:"logseq"`
 
+		Obsidian struct {
+			Enabled   *bool   `json:"enabled"`    // Whether to also write every page into an Obsidian vault
+			VaultRoot *string `json:"vault_root"` // Root of the Obsidian vault
+			Folders   struct {
+				Jira     *string `json:"jira"`     // Folder for Jira pages, defaults to "Jira"
+				Calendar *string `json:"calendar"` // Folder for calendar pages, defaults to "Calendar"
+				Assets   *string `json:"assets"`   // Folder for attachments, defaults to "Attachments"
+			} `json:"folders"`
+			GraphExcludeTag *string `json:"graph_exclude_tag"` // Tag standing in for exclude-from-graph-view, defaults to "jira"
+		} `json:"obsidian"`
+
 		Table struct {
 			Enabled *bool `json:"enabled"`
 		} `json:"table"`
@@ -85,28 +111,69 @@ type JiraOptions struct {
 		Timeline struct {
 			Enabled *bool `json:"enabled"`
 		} `json:"timeline"`
+
+		People struct {
+			Enabled  *bool   `json:"enabled"`  // Whether to write a page per person, only read from the top level options
+			Template *string `json:"template"` // Path to a text/template for the page, the built-in default is used if unset
+		} `json:"people"`
+
+		Overview struct {
+			Enabled    *bool   `json:"enabled"`     // Whether to write an overview page per project
+			Page       *string `json:"page"`        // Name of the overview page, {instance} and {project} are filled in
+			Template   *string `json:"template"`    // Path to a text/template for the page, the built-in default is used if unset
+			RecentDays *int    `json:"recent_days"` // How far back "recently updated" looks
+			Limit      *int    `json:"limit"`       // Most issues listed under each heading, 0 for no limit
+		} `json:"overview"`
 	} `json:"outputs"`
 
 	CustomFields []struct {
 		From *string `json:"from"`
 		To   *string `json:"to"`
-		As   *string `json:"as"`
+		As   *string `json:"as"`   // Conversion, see customFieldConverters
+		Path *string `json:"path"` // Path into the value for `as: json-path`, e.g. "sprint.name"
 	} `json:"custom_fields"`
 
+	ComputedFields []struct {
+		To    *string `json:"to"`    // Property to emit
+		Expr  *string `json:"expr"`  // Expression to evaluate, see expr.go
+		Table *bool   `json:"table"` // Whether to add a column for it to the table output
+	} `json:"computed_fields"`
+
+	Properties PropertyOptions `json:"properties"` // Renaming, dropping and reordering of emitted properties
+
 	Status struct {
 		Match []struct {
-			From    []*string `json:"from"`    // Statuses to translate from
-			To      *string   `json:"to"`      // Status to translate to
-			Exclude *bool     `json:"exclude"` // Whether to exclude any matching statuses
+			From    []*Matcher `json:"from"`    // Statuses to translate from, plain strings match names
+			To      *string    `json:"to"`      // Status to translate to
+			Exclude *bool      `json:"exclude"` // Whether to exclude any matching statuses
 		} `json:"match"`
-		Default *string `json:"default"`
+		Category struct {
+			New           *string `json:"new"`           // Marker for statuses in Jira's To Do category
+			Indeterminate *string `json:"indeterminate"` // Marker for statuses in Jira's In Progress category
+			Done          *string `json:"done"`          // Marker for statuses in Jira's Done category
+		} `json:"category"` // Used when no match applies
+		Default  *string `json:"default"`  // Used when neither a match nor a category applies
+		Workflow *string `json:"workflow"` // "todo" or "now" to use that workflow's markers, "auto" to follow the graph's :preferred-workflow, empty to leave them as given
 	} `json:"status"`
 
+	Priority struct {
+		Match []struct {
+			From []*string `json:"from"` // Jira priority names or IDs to translate from
+			To   *string   `json:"to"`   // Logseq priority to translate to, A, B or C
+		} `json:"match"`
+		Default *string `json:"default"` // Logseq priority for anything unmatched, empty for none
+	} `json:"priority"`
+
 	Type []struct {
-		From    []*string `json:"from"`
-		To      *string   `json:"to"`
-		Exclude *bool     `json:"exclude"`
+		From    []*Matcher `json:"from"` // Types to translate from, plain strings match descriptions
+		To      *string    `json:"to"`
+		Exclude *bool      `json:"exclude"`
 	} `json:"type"`
+
+	Filter struct {
+		Include *IssueRule `json:"include"` // Only issues matching this are processed, if set
+		Exclude *IssueRule `json:"exclude"` // Issues matching this are skipped
+	} `json:"filter"`
 }
 
 var (
@@ -114,29 +181,23 @@ var (
 	usersLock *sync.Mutex         = &sync.Mutex{}
 	parents   map[string]*string  = map[string]*string{}
 	children  map[string][]string = map[string][]string{}
+	workPool  *WorkPool
 )
 
 func (c *JiraConfig) Process(wg *errgroup.Group) (err error) {
 
-	instanceOptions, err := UnderlayOptions(&config.Jira.Options, &c.Options)
+	err = c.Connect()
 	if err != nil {
-		return errors.Wrap(err, "Couldn't merge GeneralOptions with InstanceOptions")
-	}
-	c.Options = *instanceOptions
-
-	for _, p := range c.Projects {
-		p.config = c
+		return err
 	}
 
-	if !*c.Options.Enabled {
+	if c.client == nil { // Disabled
 		return nil
 	}
 
-	c.apiLimited = &sync.Mutex{}
-
-	c.client, err = c.createClient()
+	err = ResolveFieldNames(c)
 	if err != nil {
-		return errors.Wrap(err, "Couldn't create a client")
+		return errors.Wrap(err, "Failed to resolve custom field names")
 	}
 
 	c.progress = make(map[string]*mpb.Bar)
@@ -162,66 +223,96 @@ func (c *JiraConfig) Process(wg *errgroup.Group) (err error) {
 
 	}
 
+	queues := map[string]*PoolQueue{}
+
 	for _, project := range c.Projects {
+		pbar := c.progress[*project.Key]
+		queues[*project.Key] = workPool.Queue(c, func() {
+			pbar.SetTotal(-1, true)
+		})
+	}
 
-		err = ProcessProject(wg, project)
-		if err != nil {
-			return errors.Wrap(err, "Failed processing project "+*project.Key)
-		}
+	projects, _ := errgroup.WithContext(context.Background())
+
+	for _, project := range c.Projects {
+		project := project
+		projects.Go(func() error {
+			return errors.Wrap(ProcessProject(wg, project, queues[*project.Key]), "Failed processing project "+*project.Key)
+		})
 	}
 
-	return nil
+	return projects.Wait()
 
 }
 
-func ProcessProject(wg *errgroup.Group, project *JiraProject) error {
+// ProcessProject feeds the issues of a project into its queue on the shared worker pool, and waits for them to finish
+func ProcessProject(wg *errgroup.Group, project *JiraProject, queue *PoolQueue) error {
 
 	c := project.config
 
 	lo, err := UnderlayOptions(&c.Options, &project.Options)
 	if err != nil {
+		queue.Close()
 		return errors.Wrap(err, "Couldn't merge project options over config options")
 	}
 	project.Options = *lo
 
-	errs, _ := errgroup.WithContext(context.Background())
-	if c.Connection.Parallel != nil {
-		errs.SetLimit(*c.Connection.Parallel)
-	} else {
-		errs.SetLimit(4)
+	if other := PageCollision(project); other != nil {
+		slog.Warn("Skipping project " + *project.Key + " of " + *c.Connection.BaseURL + ", its pages would overwrite those of " + *other.Connection.BaseURL + ", set instance_prefix on one of them")
+		queue.Close()
+		return nil
 	}
 
 	slog.Info("Processing Project: " + *project.Key)
 
+	CheckMarkers(project)
+
+	err = MigrateIssuePages(project)
+	if err != nil {
+		queue.Close()
+		return errors.Wrap(err, "Failed to migrate pages into namespace")
+	}
+
+	err = WriteNamespacePages(project)
+	if err != nil {
+		queue.Close()
+		return errors.Wrap(err, "Failed to write namespace pages")
+	}
+
 	issues := make(chan jira.Issue)
 
 	query := "project = " + *project.Key
 
 	if *recent {
-		if v, ok := lastRun[*project.config.Connection.BaseURL][*project.Key]; ok {
+		if v, ok := lastRun[*c.Connection.BaseURL][*project.Key]; ok {
 			query += " AND updated >= " + v.Add(time.Second*-180).Format(`"2006/01/02 15:04"`)
 		}
 	}
 
 	slog.Info("Query: " + query)
 
-	go func() error {
-		return GetIssues(query, project, issues)
-	}()
+	var searchErr error
 
-	c.progress[*project.Key].SetTotal(int64(len(issues)), false)
+	go func() {
+		searchErr = GetIssues(query, project, issues)
+	}()
 
 	for issue := range issues {
 		issue := issue
-		errs.Go(func() error {
+		queue.Go(func() error {
 			err := ProcessIssue(wg, &issue, project)
 			return errors.Wrap(err, "Failed to ProcessIssue "+issue.Key)
 		})
 	}
 
-	c.progress[*project.Key].SetTotal(-1, true)
+	queue.Close()
+
+	err = queue.Wait()
+	if searchErr != nil {
+		return errors.Wrap(searchErr, "Failed in GetIssues")
+	}
 
-	return errors.Wrap(errs.Wait(), "Goroutine failed from ProcessProject")
+	return errors.Wrap(err, "Goroutine failed from ProcessProject")
 
 }
 
@@ -239,12 +330,8 @@ func ProcessIssue(wg *errgroup.Group, issue *jira.Issue, project *JiraProject) (
 
 	// Skip if excluded by a matcher
 	for _, matcher := range project.Options.Status.Match {
-		if matcher.Exclude != nil && *matcher.Exclude {
-			for _, m := range matcher.From {
-				if *m == issue.Fields.Status.Name {
-					return nil
-				}
-			}
+		if matcher.Exclude != nil && *matcher.Exclude && MatchAny(matcher.From, StatusTarget(issue)) {
+			return nil
 		}
 	}
 
@@ -252,31 +339,62 @@ func ProcessIssue(wg *errgroup.Group, issue *jira.Issue, project *JiraProject) (
 	if err != nil {
 		return errors.Wrap(err, "Failed in GetIssue")
 	}
-	if wasCached && *skipCached {
+
+	RememberIssueUsers(fetchedIssue) // Before any text, so mentions of them resolve
+	if wasCached && *skipCached && !IsDirty(c, issue.Key) {
 		c.progress[*project.Key].IncrBy(1)
 		return nil
 	}
 
 	slog.Info("Processing Issue: " + issue.Key)
 
-	output := []string{
-		"alias:: " + issue.Key,
-		"title:: " + LogseqTitle(issue),
-		"type:: jira-ticket",
-		"jira-type:: " + JiraTypeSubstitute(project, issue),
-		"jira-project:: " + *project.Key,
-		"url:: " + *c.Connection.BaseURL + "browse/" + issue.Key,
-		"description:: " + LogseqTransform(issue.Fields.Summary),
-		"status:: " + issue.Fields.Status.Name,
-		"status-simple:: " + SimplifyStatus(project, issue),
+	page := &IssuePage{
+		Issue:        issue,
+		FullIssue:    fetchedIssue,
+		Key:          issue.Key,
+		Title:        IssuePageTitle(project, issue),
+		Type:         JiraTypeSubstitute(project, issue),
+		Project:      *project.Key,
+		URL:          *c.Connection.BaseURL + "browse/" + issue.Key,
+		Summary:      LogseqTransform(issue.Fields.Summary),
+		Status:       issue.Fields.Status.Name,
+		StatusSimple: SimplifyStatus(project, issue),
+		Created:      time.Time(issue.Fields.Created),
+		Options:      &project.Options,
+	}
+
+	if page.Title == LogseqTitle(c, issue) {
+		page.AddProperty("alias", PageKey(c, issue.Key))
+	} else { // Namespaced, so keep the plain title resolving too
+		page.AddProperty("alias", PageKey(c, issue.Key)+", [["+LogseqTitle(c, issue)+"]]")
+	}
+	page.AddProperty("title", page.Title)
+	page.AddProperty("type", "jira-ticket")
+	page.AddProperty("jira-type", LogseqValue(page.Type))
+	page.AddProperty("jira-project", page.Project)
+	page.AddProperty("url", page.URL)
+	page.AddProperty("description", LogseqValue(page.Summary))
+	page.AddProperty("status", LogseqValue(page.Status))
+	page.AddProperty("status-simple", page.StatusSimple)
+
+	if issue.Fields.Priority != nil {
+		page.JiraPriority = issue.Fields.Priority.Name
+		page.Priority = SimplifyPriority(project, issue)
+		if PriorityEnabled(project) {
+			if page.Priority != "" {
+				page.AddProperty("priority", page.Priority)
+			}
+			page.AddProperty("jira-priority", LogseqValue(page.JiraPriority))
+		}
 	}
 
 	if issue.Fields.Parent != nil {
-		output = append(output, "parent:: [["+issue.Fields.Parent.Key+"]]")
+		page.Parent = PageKey(c, issue.Fields.Parent.Key)
+		page.AddProperty("parent", "[["+page.Parent+"]]")
 	}
 
 	if *project.Options.Outputs.Logseq.ExcludeFromGraph {
-		output = append(output, "exclude-from-graph-view:: true")
+		page.AddProperty("exclude-from-graph-view", "true")
 	}
 
 	if *project.Options.Outputs.Logseq.IncludeWatchers && issue.Fields.Watches != nil && issue.Fields.Watches.WatchCount > 0 {
@@ -288,24 +406,28 @@ func ProcessIssue(wg *errgroup.Group, issue *jira.Issue, project *JiraProject) (
 
 		slices.Sort(*watchers)
 
+		page.Watchers = *watchers
+
 		if len(*watchers) > 0 {
-			output = append(output, "watchers:: "+strings.Join(*watchers, ", "))
+			page.AddProperty("watchers", strings.Join(*watchers, ", "))
 		}
 	}
 
 	if issue.Fields.Assignee != nil {
-		output = append(output, "assignee:: "+ProcessPersonName(issue.Fields.Assignee, project))
+		page.Assignee = ProcessPersonName(issue.Fields.Assignee, project)
+		page.AddProperty("assignee", page.Assignee)
 	}
 
 	if issue.Fields.Reporter != nil {
-		output = append(output, "reporter:: "+ProcessPersonName(issue.Fields.Reporter, project))
+		page.Reporter = ProcessPersonName(issue.Fields.Reporter, project)
+		page.AddProperty("reporter", page.Reporter)
 	}
 
 	if *project.Options.Outputs.Logseq.LinkDates {
-		output = append(output, "date-created:: [["+DateFormat(time.Time(issue.Fields.Created))+"]]")
+		page.AddProperty("date-created", "[["+DateFormat(page.Created)+"]]")
 	}
 
-	output = append(output, "date-created-sortable:: "+time.Time(issue.Fields.Created).Format("20060102"))
+	page.AddProperty("date-created-sortable", page.Created.Format("20060102"))
 
 	issueForDueDateCheck := issue
 	dueDateCheckDepth := 0
@@ -321,10 +443,11 @@ func ProcessIssue(wg *errgroup.Group, issue *jira.Issue, project *JiraProject) (
 			errors.Wrap(err, "Failed in GetDueDate on "+issueForDueDateCheck.Key)
 		}
 		if dueDateCheck != nil {
+			page.DueDate = dueDateCheck
 			if *project.Options.Outputs.Logseq.LinkDates {
-				output = append(output, "date-due:: [["+DateFormat(*dueDateCheck)+"]]")
+				page.AddProperty("date-due", "[["+DateFormat(*dueDateCheck)+"]]")
 			}
-			output = append(output, "date-due-sortable:: "+dueDateCheck.Format("20060102"))
+			page.AddProperty("date-due-sortable", dueDateCheck.Format("20060102"))
 			break
 		}
 
@@ -333,8 +456,7 @@ func ProcessIssue(wg *errgroup.Group, issue *jira.Issue, project *JiraProject) (
 			break
 		}
 
-		var ok bool
-		_, ok = knownIssues[issueForDueDateCheck.Fields.Parent.Key]
+		known, ok := KnownIssue(IssueID(c, issueForDueDateCheck.Fields.Parent.Key))
 
 		if !ok {
 			issueForDueDateCheck = &jira.Issue{
@@ -342,7 +464,7 @@ func ProcessIssue(wg *errgroup.Group, issue *jira.Issue, project *JiraProject) (
 				Key: issueForDueDateCheck.Fields.Parent.Key,
 			}
 		} else {
-			issueForDueDateCheck = knownIssues[issueForDueDateCheck.Fields.Parent.Key]
+			issueForDueDateCheck = known
 		}
 
 		issueForDueDateCheck, _, err, _ = GetIssue(project, issueForDueDateCheck, nil)
@@ -355,16 +477,16 @@ func ProcessIssue(wg *errgroup.Group, issue *jira.Issue, project *JiraProject) (
 	}
 
 	if hasDueDate {
-		if dueDateCheckDepth == 0 {
-			output = append(output, "date-due-explicit:: yes")
+		page.DueDateExplicit = dueDateCheckDepth == 0
+		page.DueDateSource = PageKey(c, issueForDueDateCheck.Key)
+		if page.DueDateExplicit {
+			page.AddProperty("date-due-explicit", "yes")
 		} else {
-			output = append(output, "date-due-explicit:: no")
+			page.AddProperty("date-due-explicit", "no")
 		}
-		output = append(output, "date-date-source:: [["+issueForDueDateCheck.Key+"]]")
+		page.AddProperty("date-date-source", "[["+page.DueDateSource+"]]")
 	}
 
-	hasClosedParent := false
-
 	issueForClosedCheck := issue
 
 	for {
@@ -372,8 +494,7 @@ func ProcessIssue(wg *errgroup.Group, issue *jira.Issue, project *JiraProject) (
 			break
 		}
 
-		var ok bool
-		_, ok = knownIssues[issueForClosedCheck.Fields.Parent.Key]
+		known, ok := KnownIssue(IssueID(c, issueForClosedCheck.Fields.Parent.Key))
 
 		if !ok {
 			issueForClosedCheck = &jira.Issue{
@@ -381,7 +502,7 @@ func ProcessIssue(wg *errgroup.Group, issue *jira.Issue, project *JiraProject) (
 				Key: issueForClosedCheck.Fields.Parent.Key,
 			}
 		} else {
-			issueForClosedCheck = knownIssues[issueForClosedCheck.Fields.Parent.Key]
+			issueForClosedCheck = known
 		}
 
 		issueForClosedCheck, _, err, _ = GetIssue(project, issueForClosedCheck, nil)
@@ -389,39 +510,60 @@ func ProcessIssue(wg *errgroup.Group, issue *jira.Issue, project *JiraProject) (
 			return errors.Wrap(err, "Failed in GetIssue on parent "+issueForDueDateCheck.Key)
 		}
 
-		if SimplifyStatus(project, issueForClosedCheck) == "DONE" {
-			hasClosedParent = true
+		if ClosedMarker(SimplifyStatus(project, issueForClosedCheck)) {
+			page.HasClosedParent = true
 			break
 		}
 
 	}
 
-	if hasClosedParent {
-		output = append(output, "has-closed-parent:: true")
-	} else {
-		output = append(output, "has-closed-parent:: false")
+	page.AddProperty("has-closed-parent", strconv.FormatBool(page.HasClosedParent))
+
+	page.ChildTree, page.Rollup, err = ChildRollup(project, issue)
+	if err != nil {
+		return errors.Wrap(err, "Failed in ChildRollup")
 	}
 
-	customFields, err := TranslateCustomFields(project, fetchedIssue)
+	if r := page.Rollup; r != nil {
+		page.AddProperty("children-total", strconv.Itoa(r.Total))
+		page.AddProperty("children-done", strconv.Itoa(r.Done))
+		page.AddProperty("progress-percent", strconv.Itoa(r.Percent))
+		page.AddProperty("children-overdue", strconv.Itoa(r.Overdue))
+		if r.LatestDue != nil {
+			if *project.Options.Outputs.Logseq.LinkDates {
+				page.AddProperty("latest-child-due", "[["+DateFormat(*r.LatestDue)+"]]")
+			} else {
+				page.AddProperty("latest-child-due", DateFormat(*r.LatestDue))
+			}
+			page.AddProperty("latest-child-due-sortable", r.LatestDue.Format("20060102"))
+		}
+	}
+
+	page.CustomFields, err = TranslateCustomFields(project, fetchedIssue)
 	if err != nil {
 		return errors.Wrap(err, "Failed in TranslateCustomFields")
 	}
 
-	output = append(output, customFields...)
+	page.Properties = append(page.Properties, page.CustomFields...)
 
-	line, err := ParseJiraText(project, issue.Fields.Description, fetchedIssue)
+	page.ComputedFields, err = ComputeFields(project, issue, page.DueDate, page.CustomFields)
 	if err != nil {
-		return errors.Wrap(err, "Failed in ParseJiraText")
+		return errors.Wrap(err, "Failed in ComputeFields")
 	}
 
-	output = append(output, line...)
+	page.Properties = append(page.Properties, page.ComputedFields...)
+
+	page.Description, err = ParseJiraText(project, issue.Fields.Description, fetchedIssue)
+	if err != nil {
+		return errors.Wrap(err, "Failed in ParseJiraText")
+	}
 
 	if issue.Fields.IssueLinks != nil {
 		links := map[string]([]string){}
 
 		for _, link := range issue.Fields.IssueLinks {
 			if link.OutwardIssue != nil {
-				links[link.Type.Outward] = append(links[link.Type.Outward], link.OutwardIssue.Key)
+				links[link.Type.Outward] = append(links[link.Type.Outward], PageKey(c, link.OutwardIssue.Key))
 			}
 		}
 
@@ -431,33 +573,32 @@ func ProcessIssue(wg *errgroup.Group, issue *jira.Issue, project *JiraProject) (
 		}
 		sort.Strings(keys)
 
-		caser := cases.Title(language.AmericanEnglish)
-
 		for _, linkType := range keys {
-			issues := links[linkType]
-			output = append(output, "- # "+caser.String(linkType))
-			for _, issue := range issues {
-				output = append(output, "\t- [["+issue+"]]")
-			}
+			page.Links = append(page.Links, IssueLinkGroup{
+				Type:   linkType,
+				Issues: links[linkType],
+			})
 		}
 
 	}
 
+	page.Children = KnownChildren(c, issue.Key)
+
 	if (*project.Options.Outputs.Logseq.IncludeTask &&
 		dueDateCheck != nil) ||
 		(*project.Options.Outputs.Logseq.IncludeMyTasks &&
 			issue.Fields.Assignee != nil &&
 			issue.Fields.Assignee.DisplayName == *c.Connection.DisplayName) {
-		output = append(output,
-			"- ***",
-			"- "+SimplifyStatus(project, issue)+" [[Jira Task]] [["+issue.Key+"]]",
-			"  id:: "+deterministicGUID(issue.Key))
-
-		if dueDateCheck != nil {
-			output = append(output, "\tDEADLINE: <"+dueDateCheck.Format("2006-01-02 Mon")+">",
-				"\tSCHEDULED: <"+dueDateCheck.Format("2006-01-02 Mon")+">",
-			)
-		}
+		page.Task = &IssueTask{
+			Marker:   page.StatusSimple,
+			Priority: page.Priority,
+			Key:      PageKey(c, issue.Key),
+			ID:       TaskID(c, issue.Key),
+			Due:      dueDateCheck,
+		}
+		page.Task.Properties = project.Options.Properties.Apply([]Property{
+			{"id", page.Task.ID},
+		})
 	}
 
 	if *project.Options.Outputs.Logseq.IncludeComments {
@@ -466,7 +607,6 @@ func ProcessIssue(wg *errgroup.Group, issue *jira.Issue, project *JiraProject) (
 			return errors.Wrap(err, "Failed in GetIssue")
 		}
 		if fetchedIssue.Fields.Comments != nil && len(fetchedIssue.Fields.Comments.Comments) > 0 {
-			output = append(output, "- ### Comments")
 			for _, comment := range fetchedIssue.Fields.Comments.Comments {
 				nameText := comment.Author.DisplayName
 				if *project.Options.Outputs.Logseq.LinkNames {
@@ -486,24 +626,34 @@ func ProcessIssue(wg *errgroup.Group, issue *jira.Issue, project *JiraProject) (
 					return errors.Wrap(err, "Failed to get comment update time")
 				}
 
-				output = append(output, "- "+nameText+" - Created: "+DateFormat(created)+" | Updated: "+DateFormat(updated))
-
 				lines, err := ParseJiraText(project, comment.Body, fetchedIssue)
 				if err != nil {
 					return errors.Wrap(err, "Failed in ParseJiraText")
 				}
 
-				output = append(output, PrefixStringSlice(lines, "\t")...)
-				output = append(output, "***")
+				page.Comments = append(page.Comments, IssueComment{
+					Author:  nameText,
+					Created: created,
+					Updated: updated,
+					Lines:   lines,
+				})
 			}
 		}
 	}
 
+	page.Properties = project.Options.Properties.Apply(page.Properties)
+
 	if *project.Options.Outputs.Logseq.Enabled {
-		err = WritePage(issue.Key, []byte(strings.Join(output, "\n")))
+		var contents []byte
+		contents, err = RenderIssuePage(project, page)
+		if err != nil {
+			return errors.Wrap(err, "Failed in RenderIssuePage")
+		}
+		err = WritePage(IssuePageName(project, issue.Key), contents)
 	}
 
 	if err == nil {
+		MarkProcessed(c, issue.Key)
 		c.progress[*project.Key].IncrBy(1)
 	}
 
@@ -520,11 +670,19 @@ func SaveAttachment(project *JiraProject, a *jira.Attachment) (logseqPath string
 	c := project.config
 
 	filename := "assets/jira/jira_" + a.ID + filepath.Ext(a.Filename)
+	if dir := InstanceDir(c); dir != "" {
+		filename = "assets/jira/jira_" + dir + "_" + a.ID + filepath.Ext(a.Filename)
+	}
 
 	logseqPath = "../" + filename
 	filePath := *project.Options.Outputs.Logseq.LogseqRoot + "/" + filename
 
-	if _, err := os.Stat(filePath); errors.Is(err, os.ErrNotExist) {
+	exists, err := FileExists(filePath)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to check for attachment file "+filePath)
+	}
+
+	if !exists {
 
 		o, _, err := APIWrapper(c, func(a []any) (output []any, resp *jira.Response, err error) {
 			output = make([]any, 1)
@@ -578,20 +736,74 @@ func SaveAttachment(project *JiraProject, a *jira.Attachment) (logseqPath string
 		jiraCacheHits.IncrBy(1)
 	}
 
+	if ObsidianEnabled() {
+		err = WriteObsidianAttachment(filePath)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to copy attachment into Obsidian vault")
+		}
+	}
+
 	return
 }
 
 func SimplifyStatus(project *JiraProject, i *jira.Issue) string {
 
+	return WorkflowMarker(project, statusMarker(project, i))
+}
+
+// statusMarker returns the marker for an issue's status by match, then status category, then the default
+func statusMarker(project *JiraProject, i *jira.Issue) string {
+
 	for _, matcher := range project.Options.Status.Match {
+		if MatchAny(matcher.From, StatusTarget(i)) {
+			return *matcher.To
+		}
+	}
+
+	if i.Fields.Status != nil {
+		category := project.Options.Status.Category
+		var marker *string
+		switch i.Fields.Status.StatusCategory.Key {
+		case "new":
+			marker = category.New
+		case "indeterminate":
+			marker = category.Indeterminate
+		case "done":
+			marker = category.Done
+		}
+		if marker != nil && *marker != "" {
+			return *marker
+		}
+	}
+
+	return *project.Options.Status.Default
+}
+
+// PriorityEnabled returns whether a project maps priorities, which are left off pages and tasks otherwise
+func PriorityEnabled(project *JiraProject) bool {
+	p := project.Options.Priority
+	return len(p.Match) > 0 || (p.Default != nil && *p.Default != "")
+}
+
+// SimplifyPriority returns the Logseq priority of an issue, A, B or C, or empty if it has none
+func SimplifyPriority(project *JiraProject, i *jira.Issue) string {
+
+	if i.Fields.Priority == nil {
+		return ""
+	}
+
+	for _, matcher := range project.Options.Priority.Match {
 		for _, m := range matcher.From {
-			if *m == i.Fields.Status.Name {
+			if *m == i.Fields.Priority.Name || *m == i.Fields.Priority.ID {
 				return *matcher.To
 			}
 		}
 	}
 
-	return *project.Options.Status.Default
+	if project.Options.Priority.Default == nil {
+		return ""
+	}
+	return *project.Options.Priority.Default
 }
 
 func (c *JiraConfig) createClient() (*jira.Client, error) {
@@ -611,18 +823,23 @@ func GetIssues(searchString string, project *JiraProject, issues chan jira.Issue
 
 	c := project.config
 
+	defer close(issues)
+
 	totalIssuesForProject := 0
 
-	for _, i := range knownIssues {
-		if i.Fields.Project.Key == *project.Key {
+	knownIssuesLock.RLock()
+	for id, i := range knownIssues {
+		if i.Fields.Project.Key == *project.Key && InstanceOf(id) == c {
 			totalIssuesForProject += 1
 		}
 	}
+	knownIssuesLock.RUnlock()
 
 	c.progress[*project.Key].SetTotal(int64(totalIssuesForProject), false)
 
 	last := 0
 	newIssues := []*jira.Issue{}
+	filtered := []string{} // IDs of issues found but filtered out
 	for {
 		opt := &jira.SearchOptions{
 			MaxResults: 100,
@@ -647,6 +864,16 @@ func GetIssues(searchString string, project *JiraProject, issues chan jira.Issue
 
 		total := resp.Total
 		for _, i := range chunk {
+			// Filtered out issues are dropped here, before they cost API calls or show up as known issues anywhere
+			include, err := IncludeIssue(project, &i)
+			if err != nil {
+				return errors.Wrap(err, "Failed in IncludeIssue for "+i.Key)
+			}
+			if !include {
+				slog.Debug("Skipping filtered issue " + i.Key)
+				filtered = append(filtered, IssueID(c, i.Key))
+				continue
+			}
 			totalIssuesForProject += 1
 			c.progress[*project.Key].SetTotal(int64(totalIssuesForProject), false)
 			newIssues = append(newIssues, &i)
@@ -660,174 +887,155 @@ func GetIssues(searchString string, project *JiraProject, issues chan jira.Issue
 
 	}
 
+	reprocess := []jira.Issue{}
+
+	knownIssuesLock.RLock()
 	for ik := range knownIssues { // Also want to reprocess
 		seen := false
 		for _, ni := range newIssues {
-			if ik == ni.Key {
+			if ik == IssueID(c, ni.Key) {
 				seen = true
 				break
 			}
 		}
 		if !seen {
-			if knownIssues[ik].Fields.Project.Key == *project.Key {
-				issues <- *knownIssues[ik]
+			if knownIssues[ik].Fields.Project.Key == *project.Key && InstanceOf(ik) == c {
+				include, err := IncludeIssue(project, knownIssues[ik])
+				if err != nil {
+					knownIssuesLock.RUnlock()
+					return errors.Wrap(err, "Failed in IncludeIssue for "+knownIssues[ik].Key)
+				}
+				if !include { // Known from before the filter was set
+					filtered = append(filtered, ik)
+					continue
+				}
+				reprocess = append(reprocess, *knownIssues[ik])
 			}
 		}
 	}
+	knownIssuesLock.RUnlock()
 
+	for _, i := range reprocess {
+		issues <- i
+	}
+
+	knownIssuesLock.Lock()
+	for _, id := range filtered {
+		delete(knownIssues, id)
+	}
 	for _, ni := range newIssues {
-		knownIssues[ni.Key] = ni
+		knownIssues[IssueID(c, ni.Key)] = ni
 	}
+	childIndex = nil
+	knownIssuesLock.Unlock()
 
-	close(issues)
 	return nil
 }
 
+// ParseJiraText converts Jira wiki markup, or ADF, into Logseq blocks, saving the attachments it shows
 func ParseJiraText(project *JiraProject, input string, issue *jira.Issue) ([]string, error) {
 
-	var err error
+	lines, err := RenderWikiBlocks(ParseJiraDocument(input), JiraResolver(project, issue))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed in RenderWikiBlocks")
+	}
 
-	description := strings.Split(JiraToMD(input), "\n")
-	descriptionFormatted := []string{""}
+	descriptionFormatted := append([]string{""}, lines...)
 
-	attachmentMatcher := `(?U)(!\[\]\()([^\)]+)\)`
+	if *debug {
+		// Useful for debugging original content vs converted output.
+		h1 := fnv1a.HashString64(input)
 
-	re := regexp.MustCompile(attachmentMatcher)
+		WriteFile("./debug/"+strconv.FormatUint(h1, 36)+".original", []byte(input))
+		WriteFile("./debug/"+strconv.FormatUint(h1, 36)+".formatted", []byte(JiraToMD(input)))
+		WriteFile("./debug/"+strconv.FormatUint(h1, 36)+".final", []byte(strings.Join(descriptionFormatted, "\n")))
+	}
 
-	attachmentReplacements := map[string]string{}
+	return descriptionFormatted, nil
+}
 
-	for _, l := range description {
-
-		// Images
-		matches := re.FindAllString(l, -1)
-		for _, match := range matches {
-			filename := re.ReplaceAllString(match, `$2`)
-			filepath := ""
-			if !strings.HasPrefix(filename, "http") {
-				if _, ok := attachmentReplacements[filename]; !ok {
-					found := false
-
-					_, blacklisted := attachmentBlacklist[filename]
-
-					if !blacklisted {
-						for _, attachment := range issue.Fields.Attachments {
-							if attachment.Filename == filename {
-								filepath, err = SaveAttachment(project, attachment)
-								if err != nil {
-									return nil, errors.Wrap(err, "Failed to save attachment "+attachment.ID)
-								}
-								found = true
-								break
-							}
-						}
-						if found {
-							attachmentReplacements[filename] = filepath
-						} else {
-							slog.Warn("Did not find attachment for " + filename + ", adding to blacklist")
-							attachmentReplacements[filename] = filename
-							attachmentBlacklist[filename] = true
-						}
-					} else {
-						slog.Warn(issue.Key + " - Skipping blacklisted attachment " + filename)
-						attachmentReplacements[filename] = filename
-					}
-				}
+// JiraResolver resolves the attachments, mentions and issue links in an issue's text
+func JiraResolver(project *JiraProject, issue *jira.Issue) WikiResolver {
 
-				l = strings.ReplaceAll(l, filename, attachmentReplacements[filename])
+	attachmentReplacements := map[string]string{}
 
-				l = re.ReplaceAllString(l, `![`+attachmentReplacements[filename]+`]($2)`)
+	return WikiResolver{
+		Attachment: func(filename string) (string, error) {
+			if replacement, ok := attachmentReplacements[filename]; ok {
+				return replacement, nil
 			}
 
-		}
-
-		lines := []string{l}
-		if lines[0] == "" {
-			continue
-		}
-
-		listItem := false
-
-		// Bullet list
-		matcher := `^( *)\* `
-		if regexp.MustCompile(matcher).MatchString(lines[0]) {
-			listItem = true
-
-			frontPad := regexp.MustCompile(matcher+".*").ReplaceAllString(lines[0], "$1")
-			newPad := strings.Repeat("\t", len(frontPad))
-
-			lines[0] = regexp.MustCompile(matcher).ReplaceAllString(lines[0], "")
-			lines[0] = newPad + "\t- " + lines[0]
-		}
-
-		// Ordered list
-		matcher = `^( *)[0-9]+\.( |\) )`
-		if regexp.MustCompile(matcher).MatchString(lines[0]) {
-			listItem = true
+			attachmentBlacklistLock.Lock()
+			_, blacklisted := attachmentBlacklist[filename]
+			attachmentBlacklistLock.Unlock()
 
-			frontPad := regexp.MustCompile(matcher+".*").ReplaceAllString(lines[0], "$1")
-			newPad := strings.Repeat("\t", len(frontPad)/2)
-
-			lines[0] = regexp.MustCompile(matcher).ReplaceAllString(lines[0], "")
-			lines[0] = newPad + "\t- " + lines[0]
-
-			lines = append(lines, newPad+"\t  logseq.order-list-type:: number")
-		}
-
-		if !listItem {
-			lines[0] = "- " + lines[0]
-		}
-
-		// Account ID
-		matcher = `<~accountid:([0-9]*:|)([^>]+)>`
-		if regexp.MustCompile(matcher).MatchString(lines[0]) {
-
-			accountIDs := regexp.MustCompile(matcher).FindAllString(lines[0], -1)
-
-			for _, rawAccountID := range accountIDs {
-
-				accountID := regexp.MustCompile(matcher).ReplaceAllString(rawAccountID, `$2`)
-
-				if accountID == "" {
-					slog.Info("Empty accountID in line: " + lines[0])
-				}
+			if blacklisted {
+				slog.Warn(issue.Key + " - Skipping blacklisted attachment " + filename)
+				attachmentReplacements[filename] = filename
+				return filename, nil
+			}
 
-				displayName, err := FindUser(project, accountID)
-				if err != nil {
-					if *project.Options.Outputs.Logseq.SearchUsers {
-						slog.Info(err.Error() + " - Can't find user, likely an authorization error, won't bother retrying.")
-						*project.Options.Outputs.Logseq.SearchUsers = false
-					}
-					displayName = accountID
-				} else {
-					if *project.Options.Outputs.Logseq.LinkNames {
-						displayName = "[[" + displayName + "]]"
+			for _, attachment := range issue.Fields.Attachments {
+				if attachment.Filename == filename {
+					filepath, err := SaveAttachment(project, attachment)
+					if err != nil {
+						return "", errors.Wrap(err, "Failed to save attachment "+attachment.ID)
 					}
+					attachmentReplacements[filename] = filepath
+					return filepath, nil
 				}
-
-				lines[0] = strings.Replace(lines[0], rawAccountID, displayName, 1)
 			}
-		}
 
-		// Issue links
-		for _, matcher := range issueUrlMatchers {
-			for i, line := range lines {
-				lines[i] = matcher.ReplaceAllString(line, `[[$1]]`)
+			slog.Warn("Did not find attachment for " + filename + ", adding to blacklist")
+			attachmentReplacements[filename] = filename
+			attachmentBlacklistLock.Lock()
+			attachmentBlacklist[filename] = true
+			attachmentBlacklistLock.Unlock()
+			return filename, nil
+		},
+
+		Mention: func(accountID string) string {
+			if accountID == "" {
+				slog.Info("Empty accountID in " + issue.Key)
 			}
-		}
 
-		descriptionFormatted = append(descriptionFormatted, lines...)
-	}
+			displayName, err := FindUser(project, accountID)
+			if err != nil {
+				slog.Info(err.Error() + " - Can't find user " + accountID + ", leaving the mention as is")
+				return accountID
+			}
+			displayName = PersonName(displayName) // As assignees and reporters are named, so they link to the same page
+			if *project.Options.Outputs.Logseq.LinkNames {
+				return "[[" + displayName + "]]"
+			}
+			return displayName
+		},
 
-	if *debug {
-		// Useful for debugging original content vs J2M output.
-		h1 := fnv1a.HashString64(input)
+		WideTable: func(columns int) string {
+			wide := project.Options.Outputs.Logseq.WideTables
+			if wide.As == nil || *wide.As != "query" || wide.Columns == nil || columns <= *wide.Columns {
+				return ""
+			}
+			return PageKey(project.config, issue.Key) + "-table-"
+		},
 
-		WriteFile("./debug/"+strconv.FormatUint(h1, 36)+".original", []byte(input))
-		WriteFile("./debug/"+strconv.FormatUint(h1, 36)+".formatted", []byte(strings.Join(description, "\n")))
-		WriteFile("./debug/"+strconv.FormatUint(h1, 36)+".final", []byte(strings.Join(descriptionFormatted, "\n")))
+		IssueLink: func(url string) string {
+			for _, matcher := range issueUrlMatchers {
+				if m := matcher.matcher.FindStringSubmatch(url); m != nil {
+					return PageKey(matcher.instance, m[1])
+				}
+			}
+			return ""
+		},
 	}
+}
 
-	return descriptionFormatted, nil
+// KnownIssue safely looks up an issue from the known issues
+func KnownIssue(key string) (*jira.Issue, bool) {
+	knownIssuesLock.RLock()
+	defer knownIssuesLock.RUnlock()
+	issue, ok := knownIssues[key]
+	return issue, ok
 }
 
 func PrefixStringSlice(i []string, p string) (o []string) {
@@ -837,21 +1045,29 @@ func PrefixStringSlice(i []string, p string) (o []string) {
 	return
 }
 
+// CacheDir returns the cache directory of a project's instance
+func CacheDir(project *JiraProject) string {
+	if dir := InstanceDir(project.config); dir != "" {
+		return *project.Options.Paths.CacheRoot + "/" + dir
+	}
+	return *project.Options.Paths.CacheRoot
+}
+
 func GetCachedIssuePath(project *JiraProject, sparseIssue *jira.Issue) (filepath string, dir string, err error) {
 	if sparseIssue.Fields == nil {
 		err = errors.New("No fields to parse, possibly a truly sparse issue")
 		return
 	}
-	filepath = strings.Join([]string{*project.Options.Paths.CacheRoot, sparseIssue.Key, time.Time(sparseIssue.Fields.Updated).Format("2006-01-02T15-04-05.999999999Z07-00")}, "/") + ".json"
+	filepath = strings.Join([]string{CacheDir(project), sparseIssue.Key, time.Time(sparseIssue.Fields.Updated).Format("2006-01-02T15-04-05.999999999Z07-00")}, "/") + ".json"
 	dir = regexp.MustCompile("[^/]*$").ReplaceAllString(filepath, "")
 	return
 }
 
-func GetIssue(project *JiraProject, sparseIssue *jira.Issue, fullIssueCheck *jira.Issue) (fullIssue *jira.Issue, customFields jira.CustomFields, err error, wasCached bool) {
+func GetIssue(project *JiraProject, sparseIssue *jira.Issue, fullIssueCheck *jira.Issue) (fullIssue *jira.Issue, customFields CustomFieldValues, err error, wasCached bool) {
 
 	ignoreCacheLocal := false
 
-	customFields = map[string]string{}
+	customFields = CustomFieldValues{}
 
 	c := project.config
 
@@ -863,8 +1079,8 @@ func GetIssue(project *JiraProject, sparseIssue *jira.Issue, fullIssueCheck *jir
 
 	var jsonByteValue []byte
 
-	if !ignoreCacheLocal || !*ignoreCache {
-		_, err = os.Stat(cachedFilePath)
+	if !ignoreCacheLocal && !*ignoreCache {
+		jsonByteValue, err = ReadCacheFile(cachedFilePath)
 	}
 
 	if ignoreCacheLocal || *ignoreCache || errors.Is(err, os.ErrNotExist) {
@@ -890,19 +1106,12 @@ func GetIssue(project *JiraProject, sparseIssue *jira.Issue, fullIssueCheck *jir
 			return nil, nil, errors.Wrap(err, "Failed in json.Marshal"), wasCached
 		}
 
-		cachedFilePath, dir, err := GetCachedIssuePath(project, fullIssue)
+		cachedFilePath, _, err := GetCachedIssuePath(project, fullIssue)
 		if err != nil {
 			return nil, nil, errors.Wrap(err, "Failed in GetCachedIssuePath"), wasCached
 		}
 
-		if ignoreCacheLocal {
-			err = os.MkdirAll(dir, 0755)
-			if err != nil {
-				return nil, nil, errors.Wrap(err, "Failed to make cache directory "+dir), wasCached
-			}
-		}
-
-		err = WriteFile(cachedFilePath, jsonByteValue)
+		err = WriteCacheFile(cachedFilePath, jsonByteValue)
 		if err != nil {
 			return nil, nil, errors.Wrap(err, "Failed in write file "+cachedFilePath), wasCached
 		}
@@ -913,16 +1122,6 @@ func GetIssue(project *JiraProject, sparseIssue *jira.Issue, fullIssueCheck *jir
 
 	} else {
 
-		jsonFile, err := os.Open(cachedFilePath)
-
-		if err != nil {
-			return nil, nil, errors.Wrap(err, "Failed to open file"), wasCached
-		}
-
-		defer jsonFile.Close()
-
-		jsonByteValue, _ = io.ReadAll(jsonFile)
-
 		err = json.Unmarshal(jsonByteValue, &fullIssue)
 		if err != nil {
 			return nil, nil, errors.Wrap(err, "Failed to unmarshal file "+cachedFilePath), wasCached
@@ -939,8 +1138,8 @@ func GetIssue(project *JiraProject, sparseIssue *jira.Issue, fullIssueCheck *jir
 	}
 
 	for _, customField := range project.Options.CustomFields {
-		val, ok := jsonParsed.Search("fields", *customField.From).Data().(string)
-		if val != "" && val != "<nil>" && ok {
+		val := jsonParsed.Search("fields", *customField.From).Data()
+		if val != nil {
 			customFields[*customField.From] = val
 		}
 	}
@@ -957,23 +1156,21 @@ func GetWatchers(project *JiraProject, i *jira.Issue, watchers *[]string) error
 		return nil
 	}
 
-	cachedFilePath := strings.Join([]string{*project.Options.Paths.CacheRoot, i.Key, time.Time(i.Fields.Updated).Format("2006-01-02T15-04-05.999999999Z07-00")}, "/") + "_watchers.json"
-
-	dir := regexp.MustCompile("[^/]*$").ReplaceAllString(cachedFilePath, "")
+	cachedFilePath := strings.Join([]string{CacheDir(project), i.Key, time.Time(i.Fields.Updated).Format("2006-01-02T15-04-05.999999999Z07-00")}, "/") + "_watchers.json"
 
-	err := os.MkdirAll(dir, 0755)
-	if err != nil {
-		return errors.Wrap(err, "Failed to make cache directory "+dir)
-	}
+	byteValue, err := ReadCacheFile(cachedFilePath)
 
-	if _, err := os.Stat(cachedFilePath); errors.Is(err, os.ErrNotExist) || *ignoreCache {
+	if errors.Is(err, os.ErrNotExist) || *ignoreCache {
 
 		slog.Info("Getting watchers for " + i.Key)
 		o, _, err := APIWrapper(c, func(a []any) (output []any, resp *jira.Response, err error) {
 			output = make([]any, 1)
 			output[0], resp, err = c.client.Issue.GetWatchers(context.Background(), a[0].(string))
 			if resp == nil || resp.StatusCode == 404 {
-				delete(knownIssues, i.Key)
+				knownIssuesLock.Lock()
+				delete(knownIssues, IssueID(c, i.Key))
+				childIndex = nil
+				knownIssuesLock.Unlock()
 				output = nil
 			}
 			return output, resp, errors.Wrap(err, "Couldn't get watchers for "+a[0].(string))
@@ -987,6 +1184,7 @@ func GetWatchers(project *JiraProject, i *jira.Issue, watchers *[]string) error
 			watchingUsers := o[0].(*[]jira.User)
 
 			for _, u := range *watchingUsers {
+				RememberUser(&u)
 				*watchers = append(*watchers, ProcessPersonName(&u, project))
 			}
 
@@ -995,7 +1193,7 @@ func GetWatchers(project *JiraProject, i *jira.Issue, watchers *[]string) error
 				return errors.Wrap(err, "Failed in json.Marshal")
 			}
 
-			err = WriteFile(cachedFilePath, jsonBytes)
+			err = WriteCacheFile(cachedFilePath, jsonBytes)
 			if err != nil {
 				return errors.Wrap(err, "Failed in write file "+cachedFilePath)
 			}
@@ -1007,16 +1205,6 @@ func GetWatchers(project *JiraProject, i *jira.Issue, watchers *[]string) error
 
 	} else {
 
-		jsonFile, err := os.Open(cachedFilePath)
-
-		if err != nil {
-			return errors.Wrap(err, "Failed to open file")
-		}
-
-		defer jsonFile.Close()
-
-		byteValue, _ := io.ReadAll(jsonFile)
-
 		err = json.Unmarshal(byteValue, &watchers)
 		if err != nil {
 			return errors.Wrap(err, "Failed to open file")
@@ -1045,23 +1233,25 @@ func LogseqTransform(str string) string {
 	})
 }
 
-func LogseqTitle(issue *jira.Issue) string {
-	return issue.Key + " | " + LogseqTransform(issue.Fields.Summary)
+func LogseqTitle(c *JiraConfig, issue *jira.Issue) string {
+	return PageKey(c, issue.Key) + " | " + LogseqTransform(issue.Fields.Summary)
 }
 
 func IssueMap() (parents map[string]*string, children map[string][]string) {
 	parents = map[string]*string{}
 	children = map[string][]string{}
-	for key, issue := range knownIssues {
+	for id, issue := range knownIssues {
 
-		if _, ok := children[key]; !ok {
-			children[key] = []string{}
+		if _, ok := children[id]; !ok {
+			children[id] = []string{}
 		}
 		if issue.Fields.Parent != nil {
-			parents[key] = &issue.Fields.Parent.Key
-			children[issue.Fields.Parent.Key] = append(children[issue.Fields.Parent.Key], key)
+			baseURL, _ := SplitIssueID(id)
+			parent := baseURL + "browse/" + issue.Fields.Parent.Key
+			parents[id] = &parent
+			children[parent] = append(children[parent], id)
 		} else {
-			parents[key] = nil
+			parents[id] = nil
 		}
 	}
 	return
@@ -1080,11 +1270,11 @@ func WriteIssueMap() error {
 		}
 	}
 
-	enabledProjects := map[string]bool{}
+	enabledProjects := map[string]bool{} // By base URL and project key
 
 	for _, i := range config.Jira.Instances {
 		for _, p := range i.Projects {
-			enabledProjects[*p.Key] =
+			enabledProjects[*i.Connection.BaseURL+*p.Key] =
 				(p.Options.Outputs.Logseq.Enabled != nil &&
 					*p.Options.Outputs.Logseq.Enabled)
 		}
@@ -1099,14 +1289,15 @@ func WriteIssueMap() error {
 		}
 	}
 
-	return errors.Wrap(WritePage("Jira/Item Hierarchy", []byte(strings.Join(output, "\n"))), "Failed to write page in IssueMap")
+	return errors.Wrap(WritePage(*config.Jira.Options.Outputs.Logseq.HierarchyPage, []byte(strings.Join(output, "\n"))), "Failed to write page in IssueMap")
 }
 
 func RecurseIssueMap(enabledProjects map[string]bool, target string, output *([]string), depth int) error {
-	if !enabledProjects[knownIssues[target].Fields.Project.Key] {
+	baseURL, _ := SplitIssueID(target)
+	if !enabledProjects[baseURL+knownIssues[target].Fields.Project.Key] {
 		return nil
 	}
-	*output = append(*output, strings.Repeat("\t", depth)+"- [["+LogseqTitle(knownIssues[target])+"]]")
+	*output = append(*output, strings.Repeat("\t", depth)+"- [["+LogseqTitle(InstanceOf(target), knownIssues[target])+"]]")
 	if depth == 0 {
 		*output = append(*output, "  collapsed:: true")
 	}
@@ -1126,31 +1317,34 @@ func RecurseIssueMap(enabledProjects map[string]bool, target string, output *([]
 func APIWrapper(c *JiraConfig, f func([]any) ([]any, *jira.Response, error), i []any) (output []any, resp *jira.Response, err error) {
 	var body []byte
 	var errBody error
-	retryCount := 0
-	for {
-		retryCount += 1
-		c.apiLimited.Lock()
-		c.apiLimited.Unlock() //lint:ignore SA2001 as we've only checked so we can make our API call - still risk of race condition, but lessened
-		retry := false
+	for attempt := 0; ; attempt++ {
+		c.limiter.Wait()
 		jiraApiCalls.IncrBy(1)
 		output, resp, err = f(i)
-		if resp == nil && strings.Contains(err.Error(), "404") {
+		if resp == nil && err != nil && strings.Contains(err.Error(), "404") {
 			err = nil
 			return
 		}
-		if resp != nil {
-			body, errBody = io.ReadAll(resp.Body)
-			if errBody != nil {
-				err = errors.Wrap(err, "Failed to read response body: "+errBody.Error())
+		if resp == nil { // Network error, back off and try again
+			if c.limiter.Retry(nil, attempt) {
+				continue
 			}
-		} else {
-			if retryCount < 3 {
+			return nil, nil, errors.Wrap(err, "No response")
+		}
+		c.limiter.Observe(resp.Response)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			if c.limiter.Retry(resp.Response, attempt) {
+				// Drain and close the body, so the connection is reused rather than leaked
+				_, _ = io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
 				continue
-			} else {
-				return nil, nil, errors.Wrap(err, "No response")
 			}
 		}
-		if resp.StatusCode != 200 && resp.StatusCode != 429 {
+		body, errBody = io.ReadAll(resp.Body)
+		if errBody != nil {
+			err = errors.Wrap(err, "Failed to read response body: "+errBody.Error())
+		}
+		if resp.StatusCode != 200 {
 			return nil, nil, errors.Wrap(
 				errors.Wrap(
 					err,
@@ -1158,68 +1352,29 @@ func APIWrapper(c *JiraConfig, f func([]any) ([]any, *jira.Response, error), i [
 				),
 				"APIWrapper failed due to status "+strconv.Itoa(resp.StatusCode))
 		}
-		if err != nil {
-			retry, err = CheckAPILimit(c, resp)
-			if err != nil {
-				return nil, nil, errors.Wrap(err, "Failed API limit check")
-			}
-		}
-		if !retry {
-			break
-		}
-
+		break
 	}
 	return output, resp, errors.Wrap(err, "Failed somewhere in APIWrapper")
 }
 
-func CheckAPILimit(c *JiraConfig, resp *jira.Response) (retry bool, err error) {
-	if resp.StatusCode == 200 {
-		return false, nil
-	} else if resp.StatusCode == 429 {
-		retry = true
-		c.apiLimited.Lock()
-		defer c.apiLimited.Unlock()
-		resetTime, err := time.Parse("2006-01-02T15:04Z", resp.Response.Header.Get("X-Ratelimit-Reset"))
-		if err != nil {
-			resetTime = time.Now().Add(time.Minute * 3)
-			slog.Error("Failed to parse X-Ratelimit-Reset time, defaulting to " + resetTime.Format(time.RFC822))
-			err = nil
-		}
-		resetTime = resetTime.Add(time.Second) // Add one second buffer just in case
-		slog.Warn("API calls exhausted, sleeping until " + fmt.Sprint(resetTime))
-		time.Sleep(time.Until(resetTime))
-		slog.Warn("Waking up, API should be usable again, retrying last call.")
-	} else if resp.StatusCode == 404 {
-		slog.Warn("404 not found")
-	} else {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return false, errors.Wrap(err, "Failed to read response body")
-		}
-		return false, errors.New(string(body))
-	}
-
-	return
-}
-
 func FindUser(project *JiraProject, id string) (string, error) {
 
 	c := project.config
 
+	for _, u := range config.Jira.Users {
+		if u.AccountID == id { // User is in config, which takes precedence over their name in Jira
+			return u.DisplayName, nil
+		}
+	}
+
 	usersLock.Lock()
 	defer usersLock.Unlock()
 
-	if val, ok := users[id]; ok { // User is already present
+	if val, ok := users[id]; ok { // User is in the directory
 		return val, nil
 	}
 
-	for _, u := range config.Jira.Users {
-		if u.AccountID == id { // User is in config
-			users[id] = u.DisplayName
-			return users[id], nil
-		}
-	}
-	if !*project.Options.Outputs.Logseq.SearchUsers {
+	if !*project.Options.Outputs.Logseq.SearchUsers || userMisses[id] {
 		return id, errors.New("Cannot find given user")
 	}
 
@@ -1237,13 +1392,15 @@ func FindUser(project *JiraProject, id string) (string, error) {
 		if err != nil {
 			err = errors.Wrap(err, "Failed to do request for /rest/api/3/user")
 		}
+		output[0] = ret
 
 		return output, resp, errors.Wrap(err, "Failed to get user for id "+a[0].(string))
 	}, []any{
 		id,
 	})
 	if err != nil {
-		return "", errors.Wrap(err, "Failed to run APIWrapper")
+		userMisses[id] = true
+		return id, errors.Wrap(err, "Failed to run APIWrapper")
 	}
 	foundUser := o[0].(*jira.User)
 
@@ -1255,54 +1412,49 @@ func FindUser(project *JiraProject, id string) (string, error) {
 
 func JiraTypeSubstitute(project *JiraProject, issue *jira.Issue) string {
 	for _, pair := range project.Options.Type {
-		for _, m := range pair.From {
-			if issue.Fields.Type.Description == *m {
-				return *pair.To
-			}
+		if MatchAny(pair.From, TypeTarget(issue)) {
+			return *pair.To
 		}
 	}
 	return issue.Fields.Type.Description
 }
 
-func TranslateCustomFields(project *JiraProject, issue *jira.Issue) (output []string, err error) {
+func TranslateCustomFields(project *JiraProject, issue *jira.Issue) (output []Property, err error) {
 
 	_, customFields, err, _ := GetIssue(project, issue, nil)
 	if err != nil {
-		errors.Wrap(err, "Failed in GetCustomFields")
+		return nil, errors.Wrap(err, "Failed in GetIssue")
 	}
 
 	for _, customField := range project.Options.CustomFields {
 		val, ok := customFields[*customField.From]
-		if val != "" && val != "<nil>" && ok {
-			if customField.As != nil {
-				switch *customField.As {
-				case "date_sortable":
-					if *project.Options.Outputs.Logseq.LinkDates {
-						date, err := time.Parse("2006-01-02", val)
-						if err != nil {
-							errors.Wrap(err, "Failed in time.Parse")
-						}
-						output = append(output, *customField.To+":: [["+DateFormat(date)+"]]")
-					}
-					output = append(output, *customField.To+"-sortable:: "+strings.ReplaceAll(val, "-", ""))
-				}
-			} else {
-				output = append(output, *customField.To+":: "+val)
-			}
+		if !ok {
+			continue
 		}
 
+		properties, err := ConvertCustomField(project, *customField.To, customField.As, customField.Path, val)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed in ConvertCustomField on "+issue.Key)
+		}
+
+		output = append(output, properties...)
 	}
 	return
 }
 
 func ProcessPersonName(person *jira.User, project *JiraProject) string {
-	nameText := regexp.MustCompile("[0-9]").ReplaceAllString(person.DisplayName, "")
+	nameText := PersonName(person.DisplayName)
 	if *project.Options.Outputs.Logseq.LinkNames {
 		nameText = "[[" + nameText + "]]"
 	}
 	return nameText
 }
 
+// PersonName returns the page name of a person from their display name
+func PersonName(displayName string) string {
+	return regexp.MustCompile("[0-9]").ReplaceAllString(displayName, "")
+}
+
 func GetDueDate(issue *jira.Issue, project *JiraProject) (*time.Time, error) {
 
 	if issue == nil {
@@ -1327,8 +1479,8 @@ func GetDueDate(issue *jira.Issue, project *JiraProject) (*time.Time, error) {
 			return nil, err
 		}
 
-		val, ok := customFields[customField]
-		if val != "" && val != "<nil>" && ok {
+		val, ok := customFields.String(customField)
+		if ok {
 			dateDue, err := time.Parse("2006-01-02", val)
 			if err != nil {
 				return nil, err
@@ -5,3 +5,12 @@ import "regexp"
 func PageNameToFileName(pagename string) (filename string) {
 	return regexp.MustCompile("/").ReplaceAllString(pagename, "___")
 }
+
+// LogseqFormat returns the format pages are written in, either "markdown" or "org"
+func LogseqFormat() string {
+	format := config.Jira.Options.Outputs.Logseq.Format
+	if format == nil || *format == "" {
+		return "markdown"
+	}
+	return *format
+}
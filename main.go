@@ -13,6 +13,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	jira "github.com/andygrunwald/go-jira/v2/cloud"
@@ -26,6 +27,7 @@ import (
 type Config struct {
 	Jira struct {
 		Instances []*JiraConfig `json:"instances"` // Jira instances to process
+		Parallel  *int          `json:"parallel"`  // Issues processed at once across all instances
 		Users     []struct {
 			AccountID   string `json:"account_id"`   // Account ID to match
 			DisplayName string `json:"display_name"` // Display name to print in place
@@ -55,10 +57,12 @@ var (
 	lastRun                     = map[string]map[string]*time.Time{}
 	lastRunPath                 string
 	knownIssues                 = map[string]*jira.Issue{}
+	knownIssuesLock             = &sync.RWMutex{}
 	attachmentBlacklist         = map[string]bool{}
+	attachmentBlacklistLock     = &sync.Mutex{}
 	knownIssuePath              string
 	attachmentBlacklistPath     string
-	issueUrlMatchers            = []*regexp.Regexp{}
+	issueUrlMatchers            = []IssueUrlMatcher{}
 	defaultOptions              = struct {
 		Jira JiraOptions `json:"jira"`
 	}{}
@@ -85,9 +89,24 @@ func main() {
 	ignoreCache = flag.Bool("ignore-cache", false, "Whether to ignore cached issues")
 	ignoreAttachmentBlacklist = flag.Bool("ignore-attachment-blacklist", false, "Whether to ignore blacklisted attachments")
 	skipCached = flag.Bool("skip-cached", true, "Whether to skip processing cached issues")
+	sinkKind := flag.String("sink", "fs", "Where to write output, one of fs, zip (a single archive) or memory (a dry run)")
+	sinkPath := flag.String("sink-path", "./graph.zip", "Archive to write for the zip sink")
 
 	flag.Parse()
 
+	subcommand := flag.Arg(0) // Empty to process everything, "fields" to list the fields of each instance, or "to-jira" to convert Markdown back
+
+	switch subcommand {
+	case "", "fields", "to-jira":
+	default:
+		slog.Error("Unknown subcommand `" + subcommand + "`")
+		return
+	}
+
+	if subcommand != "" {
+		*showProgress = false // Keep the progress bars out of the subcommand's output
+	}
+
 	if *verbose {
 		slog.SetLogLoggerLevel(slog.LevelInfo)
 	}
@@ -179,26 +198,73 @@ func main() {
 	}
 	config.Jira.Options = *layeredOptions
 
+	NormaliseBaseURLs()
+
+	if subcommand == "fields" {
+		err = ListFields()
+		if err != nil {
+			ErrorStackHandler(err)
+		}
+		return
+	}
+
+	if subcommand == "to-jira" {
+		LoadUsers()
+		err = ToJira(flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			ErrorStackHandler(err)
+		}
+		return
+	}
+
+	sink, err = NewSink(*sinkKind, *sinkPath) // Only output goes to the sink, the subcommands above write none
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	defer func() {
+		err := sink.Close()
+		if err != nil {
+			ErrorStackHandler(err)
+		}
+	}()
+
+	moved, err := MigrateInstanceDirs()
+	if err != nil {
+		ErrorStackHandler(err)
+		return
+	}
+	if moved { // Pages link attachments by their old names until written again
+		slog.Info("Moved the cache and attachments into per instance directories, processing every issue again")
+		*recent = false
+		*skipCached = false
+	}
+
 	lastRunPath = strings.Join([]string{*config.Jira.Options.Paths.CacheRoot, "lastRun"}, "/") + ".json"
 
 	if *recent {
 
-		jsonFile, err := os.Open(lastRunPath)
+		byteValue, err := ReadCacheFile(lastRunPath)
 
 		if err != nil {
 			slog.Warn("Failed to find or open file for last run timing, running as if you didn't specify -recent")
 			*recent = false
 		} else {
 
-			byteValue, _ := io.ReadAll(jsonFile)
-
 			err = json.Unmarshal(byteValue, &lastRun)
 			if err != nil {
 				slog.Error("Failed to unmarshal: " + err.Error())
 				return
 			}
 
-			jsonFile.Close()
+			for baseURL, projects := range lastRun { // Base URLs are now always stored with a trailing slash
+				if !strings.HasSuffix(baseURL, "/") {
+					if _, ok := lastRun[baseURL+"/"]; !ok {
+						lastRun[baseURL+"/"] = projects
+					}
+					delete(lastRun, baseURL)
+				}
+			}
 		}
 	}
 
@@ -206,63 +272,64 @@ func main() {
 
 	if !*ignoreCache {
 
-		jsonFile, err := os.Open(knownIssuePath)
+		byteValue, err := ReadCacheFile(knownIssuePath)
 
 		if err != nil {
 			slog.Warn("Failed to find or open file for known issues, assuming it hasn't been created yet")
-			lastRun = nil
+			lastRun = map[string]map[string]*time.Time{} // Nothing is known, so everything has to be fetched
 		} else {
 
-			byteValue, _ := io.ReadAll(jsonFile)
-
 			err = json.Unmarshal(byteValue, &knownIssues)
 			if err != nil {
 				slog.Error("Failed to unmarshal: " + err.Error())
 				return
 			}
 
-			jsonFile.Close()
+			knownIssues = MigrateKnownIssues(knownIssues)
 		}
 	}
 
+	LoadUsers()
+
+	for _, issue := range knownIssues {
+		RememberIssueUsers(issue)
+	}
+
 	attachmentBlacklistPath = strings.Join([]string{*config.Jira.Options.Paths.CacheRoot, "attachmentBlacklist"}, "/") + ".json"
 
 	if !*ignoreAttachmentBlacklist {
 
-		jsonFile, err := os.Open(attachmentBlacklistPath)
+		byteValue, err := ReadCacheFile(attachmentBlacklistPath)
 
 		if err != nil {
 			slog.Warn("Failed to find or open file for blacklisted attachments, assuming it hasn't been created yet")
 			attachmentBlacklist = map[string]bool{}
 		} else {
 
-			byteValue, _ := io.ReadAll(jsonFile)
-
 			err = json.Unmarshal(byteValue, &attachmentBlacklist)
 			if err != nil {
 				slog.Error("Failed to unmarshal: " + err.Error())
 				return
 			}
-
-			jsonFile.Close()
 		}
 	}
 
 	ctx := context.Background()
 	errs, _ := errgroup.WithContext(ctx)
 
+	if config.Jira.Parallel != nil {
+		workPool = NewWorkPool(*config.Jira.Parallel)
+	} else {
+		workPool = NewWorkPool(16)
+	}
+
 	// Issue links
 	for _, instance := range config.Jira.Instances {
 		for _, project := range instance.Projects {
-			urlPattern := regexp.QuoteMeta(*instance.Connection.BaseURL) + `browse/(` + regexp.QuoteMeta(*project.Key) + `-[0-9]+)`
-			matcher := ""
-			for _, pair := range [][2]string{{`[`, `]`}, {`(`, `)`}} {
-				start := regexp.QuoteMeta(pair[0])
-				end := regexp.QuoteMeta(pair[1])
-
-				matcher = matcher + start + urlPattern + `(` + end + `|[^` + end + `]+` + end + `)`
-			}
-			issueUrlMatchers = append(issueUrlMatchers, regexp.MustCompile(matcher))
+			issueUrlMatchers = append(issueUrlMatchers, IssueUrlMatcher{
+				instance: instance,
+				matcher:  regexp.MustCompile(`^` + regexp.QuoteMeta(*instance.Connection.BaseURL) + `browse/(` + regexp.QuoteMeta(*project.Key) + `-[0-9]+)(?:[?#].*)?$`),
+			})
 		}
 	}
 
@@ -291,14 +358,41 @@ func main() {
 		return
 	}
 
+	err = RefreshAncestors()
+	if err != nil {
+		ErrorStackHandler(err)
+		return
+	}
+
 	err = WriteIssueMap()
 	if err != nil {
 		ErrorStackHandler(err)
 		return
 	}
 
+	err = WriteOverviewPages()
+	if err != nil {
+		ErrorStackHandler(err)
+		return
+	}
+
+	err = WritePersonPages()
+	if err != nil {
+		ErrorStackHandler(err)
+		return
+	}
+
 	slog.Info("Jira API calls: " + strconv.Itoa(int(jiraApiCalls.Current())))
 
+	for _, instance := range config.Jira.Instances {
+		if instance.limiter == nil {
+			continue
+		}
+		throttled, waits, retries := instance.limiter.Throttled()
+		slog.Info("Jira throttling for " + *instance.Connection.BaseURL + ": " + throttled.Round(time.Millisecond).String() +
+			" over " + strconv.FormatInt(waits, 10) + " waits, " + strconv.FormatInt(retries, 10) + " retries")
+	}
+
 	if err != nil {
 		ErrorStackHandler(err)
 		return
@@ -335,7 +429,7 @@ func main() {
 		return
 	}
 
-	err = WriteFile(knownIssuePath, jsonBytes)
+	err = WriteCacheFile(knownIssuePath, jsonBytes)
 	if err != nil {
 		slog.Error("Failed in write file " + knownIssuePath)
 		return
@@ -347,13 +441,22 @@ func main() {
 		return
 	}
 
-	err = WriteFile(attachmentBlacklistPath, jsonBytes)
+	err = WriteCacheFile(attachmentBlacklistPath, jsonBytes)
 	if err != nil {
 		slog.Error("Failed in write file " + attachmentBlacklistPath)
 		return
 	}
 
+	err = SaveUsers()
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+
 	for _, instance := range config.Jira.Instances {
+		if instance.client == nil { // Disabled, so nothing was fetched
+			continue
+		}
 		if _, ok := lastRun[*instance.Connection.BaseURL]; !ok {
 			lastRun[*instance.Connection.BaseURL] = map[string]*time.Time{}
 		}
@@ -368,7 +471,7 @@ func main() {
 		return
 	}
 
-	err = WriteFile(lastRunPath, jsonBytes)
+	err = WriteCacheFile(lastRunPath, jsonBytes)
 	if err != nil {
 		slog.Error("Failed in write file " + lastRunPath)
 		return
@@ -380,7 +483,38 @@ func main() {
 
 func WritePage(title string, contents []byte) error {
 
-	return WriteFile(path.Join(*config.Jira.Options.Outputs.Logseq.LogseqRoot, "pages", "jira", PageNameToFileName(title)+".md"), contents)
+	return WritePageIn("jira", title, contents)
+
+}
+
+// WritePageIn writes a page given in Logseq Markdown to pages/<dir>, converted to the configured format
+func WritePageIn(dir string, title string, contents []byte) error {
+
+	if ObsidianEnabled() {
+		err := WriteObsidianPage(dir, title, contents)
+		if err != nil {
+			return errors.Wrap(err, "Failed to write Obsidian page "+title)
+		}
+	}
+
+	if LogseqFormat() == "org" {
+		contents = MarkdownToOrg(contents)
+	}
+
+	return WriteFile(PageFilePath(dir, title), contents)
+
+}
+
+// PageFilePath returns where WritePageIn writes a page
+func PageFilePath(dir string, title string) string {
+
+	extension := ".md"
+
+	if LogseqFormat() == "org" {
+		extension = ".org"
+	}
+
+	return path.Join(*config.Jira.Options.Outputs.Logseq.LogseqRoot, "pages", dir, PageNameToFileName(title)+extension)
 
 }
 
@@ -388,12 +522,35 @@ func WriteFile(path string, contents []byte) error {
 
 	slog.Info("Attempting to create file: " + path)
 
-	dir := regexp.MustCompile("[^/]*$").ReplaceAllString(path, "")
+	return sink.WriteFile(path, contents)
+}
 
-	err := os.MkdirAll(dir, 0755)
-	if err != nil {
-		return errors.Wrap(err, "Couldn't make directory "+dir)
-	}
+func ReadFile(path string) ([]byte, error) {
+
+	return sink.ReadFile(path)
+}
+
+// WriteCacheFile writes into the cache, which stays on the filesystem whatever the sink
+func WriteCacheFile(path string, contents []byte) error {
+
+	slog.Info("Attempting to create file: " + path)
+
+	return cacheFiles.WriteFile(path, contents)
+}
+
+func ReadCacheFile(path string) ([]byte, error) {
+
+	return cacheFiles.ReadFile(path)
+}
+
+func FileExists(path string) (bool, error) {
+
+	return sink.Exists(path)
+}
+
+func RemoveFile(path string) error {
+
+	slog.Info("Attempting to remove file: " + path)
 
-	return os.WriteFile(path, contents, 0644)
+	return sink.Remove(path)
 }
@@ -0,0 +1,740 @@
+package main
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LogseqResolver turns Logseq references back into what Jira knows them by. Any of its functions may be nil.
+type LogseqResolver struct {
+	IssueURL  func(page string) string // URL of the issue a page is, or empty if it isn't one
+	AccountID func(page string) string // Account ID of the user a page is, or empty if it isn't one
+	BlockText func(uuid string) string // Text of a referenced block, or empty if it can't be found
+}
+
+var (
+	logseqBlockMatcher    = regexp.MustCompile(`^(\t*)-(?: (.*))?$`)
+	logseqPropertyMatcher = regexp.MustCompile(`^([A-Za-z0-9_.\-]+):: ?(.*)$`)
+	markdownFence         = regexp.MustCompile("^(```+)\\s*([A-Za-z0-9_+\\-]*)\\s*$")
+	markdownHeading       = regexp.MustCompile(`^(#{1,6}) +(.*)$`)
+	markdownRule          = regexp.MustCompile(`^ {0,3}(?:(?:- *){3,}|(?:\* *){3,}|(?:_ *){3,})$`)
+	markdownListItem      = regexp.MustCompile(`^( *)([*+-]|[0-9]+[.)]) +(.*)$`)
+	markdownCellListItem  = regexp.MustCompile(`^([\x{2003}]*)(•|[0-9]+\.) (.*)$`)
+	markdownTableRule     = regexp.MustCompile(`^\|?(\s*:?-+:?\s*\|)+\s*:?-*:?\s*$`)
+	markdownAdmonition    = regexp.MustCompile(`^#\+BEGIN_([A-Z]+)\s*$`)
+	markdownBold          = regexp.MustCompile(`^\*\*(.+)\*\*$`)
+	markdownImage         = regexp.MustCompile(`^!\[([^\]]*)\]\(([^)\s]+)\)(?:\{([^}]*)\})?`)
+	markdownLink          = regexp.MustCompile(`^\[((?:[^\[\]]|\[[^\[\]]*\])*)\]\(([^)\s]*|\[\[[^\]]+\]\])\)`)
+	markdownPageRef       = regexp.MustCompile(`^#?\[\[([^\[\]]+)\]\]`)
+	markdownBlockRef      = regexp.MustCompile(`^\(\(([0-9a-f-]{36})\)\)`)
+	markdownTag           = regexp.MustCompile(`^#([^\s#\[\],.!?;:"'\\]+)`)
+	markdownHTML          = regexp.MustCompile(`^<(ins|sup|sub|cite|u|b|strong|i|em|s|del)>`)
+	markdownColor         = regexp.MustCompile(`^<span style=['"]color: ?([^'";]+);?['"]>`)
+	markdownBreak         = regexp.MustCompile(`^<br ?/?>`)
+	markdownBreaks        = regexp.MustCompile(`<br ?/?>`)
+	markdownURL           = regexp.MustCompile(`^(?:https?|ftp|file)://[^\s<>()\[\]]+`)
+)
+
+// Logseq admonitions as the Jira panel macros they are written back as
+var markdownAdmonitions = map[string]string{
+	"NOTE":      "info",
+	"PINNED":    "info",
+	"IMPORTANT": "note",
+	"CAUTION":   "warning",
+	"WARNING":   "warning",
+	"TIP":       "tip",
+	"EXAMPLE":   "panel",
+}
+
+// Inline HTML tags as the formatting they stand for
+var markdownHTMLKinds = map[string]WikiKind{
+	"ins":    WikiInserted,
+	"u":      WikiInserted,
+	"sup":    WikiSuperscript,
+	"sub":    WikiSubscript,
+	"cite":   WikiCitation,
+	"b":      WikiStrong,
+	"strong": WikiStrong,
+	"i":      WikiEmphasis,
+	"em":     WikiEmphasis,
+	"s":      WikiDeleted,
+	"del":    WikiDeleted,
+}
+
+// LogseqPage is a page of Logseq Markdown split into its outline
+type LogseqPage struct {
+	Properties []Property
+	Prelude    []string // Text before the first block, page properties aside
+	Blocks     []*logseqBlock
+}
+
+// logseqBlock is a block of a Logseq page, its first line without the bullet and the lines after without the block's
+// indentation. Properties, planning and drawers between the first line and the rest are kept apart.
+type logseqBlock struct {
+	lines      []string
+	properties []Property
+	planning   []string // SCHEDULED: and DEADLINE: lines
+	drawers    []*logseqDrawer
+	ordered    bool // Whether it is numbered, with logseq.order-list-type:: number
+	children   []*logseqBlock
+}
+
+// logseqDrawer is a drawer like :AGENDA: within a block, its lines trimmed
+type logseqDrawer struct {
+	name  string
+	lines []string
+}
+
+var (
+	logseqPlanningMatcher = regexp.MustCompile(`^(SCHEDULED|DEADLINE): <[^>]*>$`)
+	logseqDrawerMatcher   = regexp.MustCompile(`^:([A-Za-z_\-]+):$`)
+)
+
+// ParseLogseqPage splits Logseq Markdown into its blocks. Lines within fenced code are always part of the block's
+// content, never blocks or properties of their own, and keep their indentation beyond the block's.
+func ParseLogseqPage(markdown string) *LogseqPage {
+
+	page := &LogseqPage{}
+	stack := []*logseqBlock{}
+	var block *logseqBlock
+	var drawer *logseqDrawer
+	depth := 0
+	inFence := false
+
+	for _, line := range strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n") {
+
+		if !inFence {
+			if m := logseqBlockMatcher.FindStringSubmatch(line); m != nil {
+				depth = len(m[1])
+				block = &logseqBlock{lines: []string{m[2]}}
+				drawer = nil
+				if depth > len(stack) { // Skipped a level
+					depth = len(stack)
+				}
+				stack = stack[:depth]
+				if depth == 0 {
+					page.Blocks = append(page.Blocks, block)
+				} else {
+					parent := stack[depth-1]
+					parent.children = append(parent.children, block)
+				}
+				stack = append(stack, block)
+				inFence = markdownFence.MatchString(strings.TrimSpace(m[2]))
+				continue
+			}
+		}
+
+		if block == nil {
+			if len(page.Prelude) == 0 {
+				if m := logseqPropertyMatcher.FindStringSubmatch(line); m != nil {
+					page.Properties = append(page.Properties, Property{m[1], m[2]})
+					continue
+				}
+				if strings.TrimSpace(line) == "" {
+					continue
+				}
+			}
+			page.Prelude = append(page.Prelude, line)
+			continue
+		}
+
+		content := strings.TrimPrefix(line, strings.Repeat("\t", depth))
+		content = strings.TrimPrefix(strings.TrimPrefix(content, " "), " ")
+		trimmed := strings.TrimSpace(content)
+
+		if !inFence && len(block.lines) == 1 { // Before the content starts
+			if drawer != nil {
+				if trimmed == ":END:" {
+					drawer = nil
+				} else {
+					drawer.lines = append(drawer.lines, trimmed)
+				}
+				continue
+			}
+			if m := logseqDrawerMatcher.FindStringSubmatch(trimmed); m != nil && m[1] != "END" {
+				drawer = &logseqDrawer{name: m[1]}
+				block.drawers = append(block.drawers, drawer)
+				continue
+			}
+			if logseqPlanningMatcher.MatchString(trimmed) {
+				block.planning = append(block.planning, trimmed)
+				continue
+			}
+			if m := logseqPropertyMatcher.FindStringSubmatch(trimmed); m != nil {
+				block.properties = append(block.properties, Property{m[1], m[2]})
+				if m[1] == "logseq.order-list-type" && m[2] == "number" {
+					block.ordered = true
+				}
+				continue
+			}
+		}
+
+		if markdownFence.MatchString(trimmed) {
+			inFence = !inFence
+		}
+
+		block.lines = append(block.lines, content)
+	}
+
+	return page
+}
+
+// ParseLogseq parses Logseq Markdown into the same tree wiki markup is parsed into.
+// Top level blocks become paragraphs, headings and the like, and the blocks under them become lists.
+// Text before the first block, page properties aside, is taken as plain Markdown.
+func ParseLogseq(markdown string, resolver LogseqResolver) *WikiNode {
+
+	p := &markdownParser{resolver: resolver}
+	page := ParseLogseqPage(markdown)
+	roots := page.Blocks
+
+	doc := &WikiNode{Kind: WikiDocument, Children: p.blocks(page.Prelude)}
+
+	for i := 0; i < len(roots); i++ {
+		if roots[i].ordered { // Numbered blocks at the top are a list of their own
+			j := i
+			for j < len(roots) && roots[j].ordered {
+				j++
+			}
+			doc.Children = append(doc.Children, p.lists(roots[i:j])...)
+			i = j - 1
+			continue
+		}
+		doc.Children = append(doc.Children, p.blocks(logseqUnmarked(roots[i].lines))...)
+		doc.Children = append(doc.Children, p.lists(roots[i].children)...)
+	}
+
+	return doc
+}
+
+// logseqUnmarked turns a task marker neutralised on import back into text in a block's first line
+func logseqUnmarked(lines []string) []string {
+	if len(lines) == 0 {
+		return lines
+	}
+	return append([]string{LogseqBlockUnstart(lines[0])}, lines[1:]...)
+}
+
+type markdownParser struct {
+	resolver LogseqResolver
+	keep     bool // Keep Logseq's references and escapes as they are, converting between its own formats
+}
+
+// lists turns blocks nested under another into lists, a new one starting wherever numbering starts or stops
+func (p *markdownParser) lists(blocks []*logseqBlock) (lists []*WikiNode) {
+	for i, b := range blocks {
+		if i == 0 || b.ordered != blocks[i-1].ordered {
+			lists = append(lists, &WikiNode{Kind: WikiList, Ordered: b.ordered})
+		}
+		children := p.blocks(logseqUnmarked(b.lines))
+		if len(children) == 0 || children[0].Kind != WikiParagraph {
+			children = append([]*WikiNode{{Kind: WikiParagraph}}, children...)
+		}
+		children = append(children, p.lists(b.children)...)
+		list := lists[len(lists)-1]
+		list.Children = append(list.Children, &WikiNode{Kind: WikiListItem, Children: children})
+	}
+	return lists
+}
+
+// blocks parses the Markdown within a block
+func (p *markdownParser) blocks(lines []string) (blocks []*WikiNode) {
+
+	paragraph := []string{}
+	flush := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, &WikiNode{Kind: WikiParagraph, Children: p.inline(strings.Join(paragraph, "\n"))})
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "" {
+			flush()
+			continue
+		}
+
+		if m := markdownFence.FindStringSubmatch(trimmed); m != nil {
+			flush()
+			code := []string{}
+			for i++; i < len(lines) && strings.TrimSpace(lines[i]) != m[1]; i++ {
+				code = append(code, lines[i])
+			}
+			node := &WikiNode{Kind: WikiCode, Text: strings.Join(code, "\n"), Params: map[string]string{}}
+			if m[2] != "" {
+				node.Params["language"] = m[2]
+			}
+			blocks = append(blocks, node)
+			continue
+		}
+
+		if m := markdownAdmonition.FindStringSubmatch(trimmed); m != nil {
+			flush()
+			body := []string{}
+			for i++; i < len(lines) && strings.TrimSpace(lines[i]) != "#+END_"+m[1]; i++ {
+				body = append(body, lines[i])
+			}
+			if m[1] == "QUOTE" {
+				blocks = append(blocks, &WikiNode{Kind: WikiQuote, Children: p.blocks(body)})
+				continue
+			}
+			name, ok := markdownAdmonitions[m[1]]
+			if !ok {
+				name = "panel"
+			}
+			params := map[string]string{}
+			if p.keep {
+				params["admonition"] = m[1]
+			}
+			if len(body) > 0 {
+				if title := markdownBold.FindStringSubmatch(strings.TrimSpace(body[0])); title != nil {
+					params["title"] = title[1]
+					body = body[1:]
+				}
+			}
+			blocks = append(blocks, &WikiNode{Kind: WikiPanel, Name: name, Params: params, Children: p.blocks(body)})
+			continue
+		}
+
+		if m := markdownHeading.FindStringSubmatch(trimmed); m != nil {
+			flush()
+			blocks = append(blocks, &WikiNode{Kind: WikiHeading, Level: len(m[1]), Children: p.inline(m[2])})
+			continue
+		}
+
+		if markdownRule.MatchString(line) {
+			flush()
+			blocks = append(blocks, &WikiNode{Kind: WikiRule})
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, ">") {
+			flush()
+			quoted := []string{}
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quoted = append(quoted, strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">"), " "))
+			}
+			i--
+			blocks = append(blocks, &WikiNode{Kind: WikiQuote, Children: p.blocks(quoted)})
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "|") {
+			flush()
+			rows := []string{}
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				rows = append(rows, strings.TrimSpace(lines[i]))
+			}
+			i--
+			blocks = append(blocks, p.table(rows))
+			continue
+		}
+
+		if markdownListItem.MatchString(line) {
+			flush()
+			items := []wikiListLine{}
+			indents := []int{}  // Indentation of each open level
+			markers := []byte{} // Marker of each open level
+			for ; i < len(lines); i++ {
+				m := markdownListItem.FindStringSubmatch(lines[i])
+				if m == nil {
+					if strings.TrimSpace(lines[i]) == "" || len(items) == 0 {
+						break
+					}
+					items[len(items)-1].text += "\n" + strings.TrimSpace(lines[i]) // Continues the item before
+					continue
+				}
+				indent := len(m[1])
+				for len(indents) > 0 && indent < indents[len(indents)-1] {
+					indents = indents[:len(indents)-1]
+					markers = markers[:len(markers)-1]
+				}
+				marker := byte('*')
+				if m[2][0] >= '0' && m[2][0] <= '9' {
+					marker = '#'
+				}
+				if len(indents) == 0 || indent > indents[len(indents)-1] {
+					indents = append(indents, indent)
+					markers = append(markers, marker)
+				} else {
+					markers[len(markers)-1] = marker
+				}
+				items = append(items, wikiListLine{marker: string(markers), text: m[3]})
+			}
+			i--
+			blocks = append(blocks, buildWikiLists(items, p.inline)...)
+			continue
+		}
+
+		paragraph = append(paragraph, line)
+	}
+
+	flush()
+
+	return blocks
+}
+
+// table parses a Markdown table, dropping a header row left empty for want of one
+func (p *markdownParser) table(rows []string) *WikiNode {
+
+	table := &WikiNode{Kind: WikiTable}
+	header := len(rows) > 1 && markdownTableRule.MatchString(rows[1])
+
+	for i, row := range rows {
+		if header && i == 1 {
+			continue
+		}
+		cells := markdownCells(row)
+		if header && i == 0 && strings.TrimSpace(strings.Join(cells, "")) == "" {
+			continue
+		}
+		r := &WikiNode{Kind: WikiRow}
+		for _, cell := range cells {
+			r.Children = append(r.Children, &WikiNode{Kind: WikiCell, Header: header && i == 0, Children: p.cell(cell)})
+		}
+		table.Children = append(table.Children, r)
+	}
+
+	return table
+}
+
+// markdownCells splits a table row on the bars that aren't escaped or in code
+func markdownCells(row string) (cells []string) {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, `\|`) {
+		row = row[:len(row)-1]
+	}
+
+	cell := strings.Builder{}
+	inCode := false
+	for i := 0; i < len(row); i++ {
+		switch {
+		case row[i] == '\\' && i+1 < len(row) && row[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case row[i] == '`':
+			inCode = !inCode
+			cell.WriteByte('`')
+		case row[i] == '|' && !inCode:
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(row[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// cell parses a table cell, where <br> separates lines, and lines marked with • or numbers are list items
+func (p *markdownParser) cell(text string) []*WikiNode {
+	lines := []string{}
+	for _, l := range markdownBreaks.Split(text, -1) {
+		if m := markdownCellListItem.FindStringSubmatch(l); m != nil {
+			indent := strings.Repeat("  ", strings.Count(m[1], "\u2003"))
+			marker := "*"
+			if m[2] != "•" {
+				marker = "1."
+			}
+			l = indent + marker + " " + m[3]
+		}
+		lines = append(lines, l)
+	}
+	return p.blocks(lines)
+}
+
+// inline parses Markdown text, resolving page and block references as it goes
+func (p *markdownParser) inline(s string) (nodes []*WikiNode) {
+
+	text := strings.Builder{}
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, &WikiNode{Kind: WikiText, Text: text.String()})
+			text.Reset()
+		}
+	}
+	emit := func(n *WikiNode) {
+		flush()
+		nodes = append(nodes, n)
+	}
+
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		prev, _ := utf8.DecodeLastRuneInString(s[:i])
+		atWordStart := i == 0 || !(unicode.IsLetter(prev) || unicode.IsDigit(prev))
+
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && strings.ContainsRune("\\`*_{}[]()#+-.!|~^$<>:", rune(rest[1])):
+			if p.keep {
+				emit(&WikiNode{Kind: WikiEscape, Text: rest[1:2]})
+			} else {
+				text.WriteByte(rest[1])
+			}
+			i += 2
+			continue
+
+		case rest[0] == '\n':
+			emit(&WikiNode{Kind: WikiBreak})
+			i++
+			continue
+
+		case rest[0] == '`':
+			fence := rest[:len(rest)-len(strings.TrimLeft(rest, "`"))]
+			if j := strings.Index(rest[len(fence):], fence); j >= 0 {
+				code := rest[len(fence) : len(fence)+j]
+				if len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' {
+					code = code[1 : len(code)-1]
+				}
+				emit(&WikiNode{Kind: WikiMonospace, Text: code})
+				i += 2*len(fence) + j
+				continue
+			}
+
+		case rest[0] == '!':
+			if m := markdownImage.FindStringSubmatch(rest); m != nil {
+				node := markdownImageNode(m[1], m[2], m[3])
+				if p.keep {
+					node.Text = m[2]
+				}
+				emit(node)
+				i += len(m[0])
+				continue
+			}
+
+		case rest[0] == '[':
+			if m := markdownPageRef.FindStringSubmatch(rest); m != nil {
+				emit(p.pageRef(m[1], nil))
+				i += len(m[0])
+				continue
+			}
+			if m := markdownLink.FindStringSubmatch(rest); m != nil {
+				emit(p.link(m[1], m[2]))
+				i += len(m[0])
+				continue
+			}
+
+		case rest[0] == '(':
+			if m := markdownBlockRef.FindStringSubmatch(rest); m != nil {
+				if p.keep {
+					emit(&WikiNode{Kind: WikiBlockRef, Text: m[1]})
+					i += len(m[0])
+					continue
+				}
+				if p.resolver.BlockText != nil {
+					if block := p.resolver.BlockText(m[1]); block != "" {
+						flush()
+						nodes = append(nodes, p.inline(block)...)
+						i += len(m[0])
+						continue
+					}
+				}
+			}
+
+		case rest[0] == '#' && atWordStart:
+			if m := markdownPageRef.FindStringSubmatch(rest); m != nil {
+				node := p.pageRef(m[1], nil)
+				if p.keep {
+					node.Name = "#"
+				}
+				emit(node)
+				i += len(m[0])
+				continue
+			}
+			if m := markdownTag.FindStringSubmatch(rest); m != nil && !strings.ContainsAny(m[1][:1], "0123456789") {
+				node := p.pageRef(m[1], nil)
+				if p.keep {
+					node.Name = "#"
+				} else if node.Kind == WikiText { // Tags that aren't issues or people read as they did
+					node.Text = m[0]
+				}
+				emit(node)
+				i += len(m[0])
+				continue
+			}
+
+		case rest[0] == '{' && p.keep && strings.HasPrefix(rest, "{{"):
+			if j := strings.Index(rest, "}}"); j >= 0 {
+				emit(&WikiNode{Kind: WikiMacro, Text: rest[:j+2]})
+				i += j + 2
+				continue
+			}
+
+		case rest[0] == '<':
+			if m := markdownBreak.FindString(rest); m != "" {
+				emit(&WikiNode{Kind: WikiBreak})
+				i += len(m)
+				continue
+			}
+			if m := markdownHTML.FindStringSubmatch(rest); m != nil {
+				closing := "</" + m[1] + ">"
+				if j := strings.Index(rest[len(m[0]):], closing); j >= 0 {
+					emit(&WikiNode{Kind: markdownHTMLKinds[m[1]], Children: p.inline(rest[len(m[0]) : len(m[0])+j])})
+					i += len(m[0]) + j + len(closing)
+					continue
+				}
+			}
+			if m := markdownColor.FindStringSubmatch(rest); m != nil {
+				if j := strings.Index(rest[len(m[0]):], "</span>"); j >= 0 {
+					emit(&WikiNode{Kind: WikiColor, Params: map[string]string{"color": strings.TrimSpace(m[1])}, Children: p.inline(rest[len(m[0]) : len(m[0])+j])})
+					i += len(m[0]) + j + len("</span>")
+					continue
+				}
+			}
+
+		case atWordStart && markdownURL.MatchString(rest):
+			url := strings.TrimRight(markdownURL.FindString(rest), ".,;:!?'\"")
+			emit(&WikiNode{Kind: WikiLink, Text: url})
+			i += len(url)
+			continue
+		}
+
+		if kind, delimiter, inner, ok := markdownEmphasis(rest, atWordStart); ok {
+			emit(&WikiNode{Kind: kind, Children: p.inline(inner)})
+			i += 2*len(delimiter) + len(inner)
+			continue
+		}
+
+		_, size := utf8.DecodeRuneInString(rest)
+		text.WriteString(rest[:size])
+		i += size
+	}
+
+	flush()
+
+	return nodes
+}
+
+// markdownEmphasis matches bold, italics or strikethrough at the start of s
+func markdownEmphasis(s string, atWordStart bool) (kind WikiKind, delimiter string, inner string, ok bool) {
+
+	for _, d := range []struct {
+		delimiter string
+		kind      WikiKind
+	}{
+		{"**", WikiStrong},
+		{"__", WikiStrong},
+		{"~~", WikiDeleted},
+		{"*", WikiEmphasis},
+		{"_", WikiEmphasis},
+	} {
+		if !strings.HasPrefix(s, d.delimiter) {
+			continue
+		}
+		if d.delimiter[0] == '_' && !atWordStart { // Underscores within words are just underscores
+			return
+		}
+		after := s[len(d.delimiter):]
+		if after == "" || unicode.IsSpace(rune(after[0])) || (len(d.delimiter) == 1 && after[0] == d.delimiter[0]) {
+			return
+		}
+
+		for j := 1; j < len(after); j++ {
+			if after[j] == '`' { // Code spans hide what's in them
+				if k := strings.IndexByte(after[j+1:], '`'); k >= 0 {
+					j += k + 1
+					continue
+				}
+			}
+			if !strings.HasPrefix(after[j:], d.delimiter) {
+				continue
+			}
+			if len(d.delimiter) == 1 && strings.HasPrefix(after[j:], d.delimiter+d.delimiter) { // Part of a double delimiter
+				j++
+				continue
+			}
+			if unicode.IsSpace(rune(after[j-1])) {
+				continue
+			}
+			end := after[j+len(d.delimiter):]
+			if d.delimiter[0] == '_' && end != "" && (unicode.IsLetter(rune(end[0])) || unicode.IsDigit(rune(end[0]))) {
+				continue
+			}
+			return d.kind, d.delimiter, after[:j], true
+		}
+		return
+	}
+
+	return
+}
+
+// markdownImageNode turns an image back into an attachment's filename, or leaves its URL.
+// Attachments are saved under their IDs, so the alt text, their original filename, is preferred.
+func markdownImageNode(alt string, source string, attributes string) *WikiNode {
+
+	node := &WikiNode{Kind: WikiImage, Text: source, Params: map[string]string{}}
+
+	if !markdownURL.MatchString(source) {
+		node.Text = path.Base(source)
+		if alt != "" {
+			node.Text = alt
+		}
+	}
+
+	for _, attribute := range strings.Split(attributes, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(attribute), " ")
+		key = strings.TrimPrefix(key, ":")
+		if (key == "width" || key == "height") && value != "" {
+			if _, err := strconv.Atoi(value); err == nil {
+				node.Params[key] = value
+			}
+		}
+	}
+
+	return node
+}
+
+// link parses a Markdown link, which may point at a page, or an asset, as well as a URL
+func (p *markdownParser) link(label string, target string) *WikiNode {
+
+	children := p.inline(label)
+
+	if strings.HasPrefix(target, "[[") {
+		return p.pageRef(strings.Trim(target, "[]"), children)
+	}
+
+	if !p.keep && !markdownURL.MatchString(target) && !strings.HasPrefix(target, "mailto:") && strings.Contains(target, "assets/") {
+		filename := path.Base(target)
+		if strings.Contains(label, ".") {
+			filename = label
+			children = nil
+		}
+		return &WikiNode{Kind: WikiAttachment, Text: filename, Children: children}
+	}
+
+	return &WikiNode{Kind: WikiLink, Text: target, Children: children}
+}
+
+// pageRef resolves a page reference to an issue link or a mention, or leaves its name
+func (p *markdownParser) pageRef(page string, label []*WikiNode) *WikiNode {
+
+	if p.keep {
+		return &WikiNode{Kind: WikiPageRef, Text: page, Children: label}
+	}
+
+	name, _, _ := strings.Cut(page, " | ") // Issue pages are titled `KEY | summary`
+	name = strings.TrimSpace(name)
+
+	if p.resolver.IssueURL != nil {
+		if url := p.resolver.IssueURL(name); url != "" {
+			return &WikiNode{Kind: WikiLink, Text: url, Children: label}
+		}
+	}
+
+	if p.resolver.AccountID != nil && label == nil {
+		if id := p.resolver.AccountID(page); id != "" {
+			return &WikiNode{Kind: WikiMention, Text: id}
+		}
+	}
+
+	if label != nil {
+		return &WikiNode{Kind: WikiStrong, Children: label}
+	}
+	return &WikiNode{Kind: WikiText, Text: page}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"sync"
+
+	jira "github.com/andygrunwald/go-jira/v2/cloud"
+	"github.com/pkg/errors"
+)
+
+// Matcher picks out a Jira status or issue type, or a calendar event by title.
+// Every field given has to match. A plain string is the older form, which matches
+// exactly what the option always matched against: status names, type descriptions or event titles.
+type Matcher struct {
+	ID          *string `json:"id"`          // Jira ID
+	Name        *string `json:"name"`        // Exact name
+	Description *string `json:"description"` // Exact description
+	Regex       *string `json:"regex"`       // Regular expression matched against the name, or failing that the description
+
+	Legacy *string `json:"-"` // Set when given as a plain string
+}
+
+// MatchTarget is what a Matcher is tested against
+type MatchTarget struct {
+	ID          string
+	Name        string
+	Description string
+	Legacy      string // What a plain string matches
+}
+
+// StatusTarget matches an issue's status, plain strings match its name
+func StatusTarget(issue *jira.Issue) MatchTarget {
+	s := issue.Fields.Status
+	if s == nil {
+		return MatchTarget{}
+	}
+	return MatchTarget{ID: s.ID, Name: s.Name, Description: s.Description, Legacy: s.Name}
+}
+
+// TypeTarget matches an issue's type, plain strings match its description
+func TypeTarget(issue *jira.Issue) MatchTarget {
+	t := issue.Fields.Type
+	return MatchTarget{ID: t.ID, Name: t.Name, Description: t.Description, Legacy: t.Description}
+}
+
+var (
+	matcherRegexps     = map[string]*regexp.Regexp{}
+	matcherRegexpsLock = &sync.Mutex{}
+)
+
+func (m *Matcher) UnmarshalJSON(data []byte) error {
+	var legacy string
+	if json.Unmarshal(data, &legacy) == nil {
+		m.Legacy = &legacy
+		return nil
+	}
+
+	type fields Matcher // Without this method, so it doesn't recurse
+	err := json.Unmarshal(data, (*fields)(m))
+	if err != nil {
+		return err
+	}
+
+	if m.Regex != nil { // Compiled as the config loads, so a bad pattern stops the run rather than never matching
+		_, err = matcherRegexp(*m.Regex)
+	}
+	return err
+}
+
+func (m Matcher) MarshalJSON() ([]byte, error) {
+	if m.Legacy != nil {
+		return json.Marshal(*m.Legacy)
+	}
+	type fields Matcher
+	return json.Marshal(fields(m))
+}
+
+// String describes the matcher for logs
+func (m *Matcher) String() string {
+	b, _ := m.MarshalJSON()
+	return string(b)
+}
+
+// Match returns whether every field of the matcher matches the target
+func (m *Matcher) Match(t MatchTarget) bool {
+
+	if m.Legacy != nil {
+		return *m.Legacy == t.Legacy
+	}
+
+	if m.ID == nil && m.Name == nil && m.Description == nil && m.Regex == nil {
+		return false
+	}
+
+	if (m.ID != nil && *m.ID != t.ID) ||
+		(m.Name != nil && *m.Name != t.Name) ||
+		(m.Description != nil && *m.Description != t.Description) {
+		return false
+	}
+
+	if m.Regex != nil {
+		r, err := matcherRegexp(*m.Regex)
+		if err != nil || !(r.MatchString(t.Name) || r.MatchString(t.Description)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MatchAny returns whether any of the matchers match the target
+func MatchAny(matchers []*Matcher, t MatchTarget) bool {
+	for _, m := range matchers {
+		if m != nil && m.Match(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// matcherRegexp compiles a pattern once
+func matcherRegexp(pattern string) (*regexp.Regexp, error) {
+	matcherRegexpsLock.Lock()
+	defer matcherRegexpsLock.Unlock()
+
+	if r, ok := matcherRegexps[pattern]; ok {
+		return r, nil
+	}
+
+	r, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.Wrap(err, "Bad matcher regex `"+pattern+"`")
+	}
+	matcherRegexps[pattern] = r
+
+	return r, nil
+}
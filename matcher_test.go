@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira/v2/cloud"
+)
+
+func TestMatcherTargets(t *testing.T) {
+
+	issue := &jira.Issue{Fields: &jira.IssueFields{Type: jira.IssueType{ID: "10001", Name: "Bug", Description: "A problem"}}}
+
+	tests := []struct {
+		matcher  string
+		target   MatchTarget
+		expected bool
+	}{
+		{`"A problem"`, TypeTarget(issue), true}, // Plain strings match by description, for excludes too
+		{`"Bug"`, TypeTarget(issue), false},
+		{`{"name": "Bug"}`, TypeTarget(issue), true},
+		{`{"id": "10001", "name": "Story"}`, TypeTarget(issue), false},
+		{`{"regex": "(?i)^bug$"}`, TypeTarget(issue), true},
+		{`{"regex": "problem"}`, TypeTarget(issue), true},
+		{`{}`, TypeTarget(issue), false},
+	}
+
+	for _, test := range tests {
+		m := &Matcher{}
+		if err := json.Unmarshal([]byte(test.matcher), m); err != nil {
+			t.Fatal(err)
+		}
+		if m.Match(test.target) != test.expected {
+			t.Errorf("%s matching %+v, expected %v", test.matcher, test.target, test.expected)
+		}
+	}
+}
+
+func TestMatcherBadRegex(t *testing.T) {
+	options := JiraOptions{}
+	err := json.Unmarshal([]byte(`{"type": [{"from": [{"regex": "(unclosed"}], "exclude": true}]}`), &options)
+	if err == nil || !strings.Contains(err.Error(), "Bad matcher regex") {
+		t.Errorf("expected the config to fail to load, got %v", err)
+	}
+}
+
+func TestTypeExcludes(t *testing.T) {
+
+	issue := &jira.Issue{Fields: &jira.IssueFields{Type: jira.IssueType{ID: "10001", Name: "Bug", Description: "A problem"}}}
+
+	tests := []struct {
+		from     string
+		included bool
+	}{
+		{`["A problem"]`, false},
+		{`["Bug"]`, true}, // Plain strings are descriptions, as in substitutions
+		{`[{"name": "Bug"}]`, false},
+	}
+
+	for _, test := range tests {
+		project := &JiraProject{}
+		if err := json.Unmarshal([]byte(`{"type": [{"from": `+test.from+`, "exclude": true}]}`), &project.Options); err != nil {
+			t.Fatal(err)
+		}
+		included, err := IncludeIssue(project, issue)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if included != test.included {
+			t.Errorf("excluding %s, included %v, expected %v", test.from, included, test.included)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	jira "github.com/andygrunwald/go-jira/v2/cloud"
+	"github.com/pkg/errors"
+)
+
+var (
+	namespacePages     = map[string]bool{} // Namespace pages already written this run
+	namespacePagesLock = &sync.Mutex{}
+	legacyTitleMatcher = regexp.MustCompile(`(?m)^(title:: |:title: )(.*)$`)
+	legacyAliasMatcher = regexp.MustCompile(`(?m)^(alias:: |:alias: )(.*)$`)
+)
+
+// InstanceName returns the name used for {instance} in namespaces
+func InstanceName(c *JiraConfig) string {
+	if c.Connection.Name != nil && *c.Connection.Name != "" {
+		return *c.Connection.Name
+	}
+	u, err := url.Parse(*c.Connection.BaseURL)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	name, _, _ := strings.Cut(u.Hostname(), ".")
+	return name
+}
+
+// PageNamespace returns the namespace of a project's issue pages with placeholders filled in, or "" for none
+func PageNamespace(project *JiraProject) string {
+	ns := project.Options.Outputs.Logseq.Namespace
+	if ns == nil {
+		return ""
+	}
+	return strings.Trim(ExpandPageName(project, *ns), "/")
+}
+
+// ExpandPageName fills in the {instance} and {project} placeholders of a configured page name
+func ExpandPageName(project *JiraProject, name string) string {
+	return strings.NewReplacer(
+		"{instance}", InstanceName(project.config),
+		"{project}", *project.Key,
+	).Replace(name)
+}
+
+// IssuePageName returns the name of an issue's page, e.g. "work/acme/PROJ/PROJ-123", or just the key without a namespace
+func IssuePageName(project *JiraProject, key string) string {
+	if ns := PageNamespace(project); ns != "" {
+		return ns + "/" + PageKey(project.config, key)
+	}
+	return PageKey(project.config, key)
+}
+
+// IssuePageTitle returns the title:: of an issue's page
+func IssuePageTitle(project *JiraProject, issue *jira.Issue) string {
+	if PageNamespace(project) != "" {
+		return IssuePageName(project, issue.Key)
+	}
+	return LogseqTitle(project.config, issue)
+}
+
+// WriteNamespacePages creates a page for each level of a project's namespace.
+// Pages that already exist are left alone, they may well be the user's own.
+func WriteNamespacePages(project *JiraProject) error {
+
+	ns := PageNamespace(project)
+	if ns == "" || !*project.Options.Outputs.Logseq.Enabled {
+		return nil
+	}
+
+	namespacePagesLock.Lock()
+	defer namespacePagesLock.Unlock()
+
+	segments := strings.Split(ns, "/")
+
+	for i := range segments {
+		name := strings.Join(segments[:i+1], "/")
+
+		if namespacePages[name] {
+			continue
+		}
+		namespacePages[name] = true
+
+		exists := false
+		for _, dir := range []string{"", "jira"} { // The user's own pages live directly in pages/
+			e, err := FileExists(PageFilePath(dir, name))
+			if err != nil {
+				return errors.Wrap(err, "Failed to check for namespace page "+name)
+			}
+			exists = exists || e
+		}
+		if exists {
+			continue
+		}
+
+		properties := []Property{{"type", "jira-namespace"}}
+		if *project.Options.Outputs.Logseq.ExcludeFromGraph {
+			properties = append(properties, Property{"exclude-from-graph-view", "true"})
+		}
+
+		lines := []string{}
+		for _, p := range project.Options.Properties.Apply(properties) {
+			lines = append(lines, p.String())
+		}
+
+		err := WritePage(name, []byte(strings.Join(lines, "\n")))
+		if err != nil {
+			return errors.Wrap(err, "Failed to write namespace page "+name)
+		}
+	}
+
+	return nil
+}
+
+// MigrateIssuePages moves a project's pages from pages/jira/KEY.md into its namespace, or to their prefixed name.
+// The old title is kept as an alias, alongside the key, so existing references still resolve.
+func MigrateIssuePages(project *JiraProject) error {
+
+	if PageNamespace(project) == "" && PageKey(project.config, "") == "" {
+		return nil
+	}
+
+	issues := []*jira.Issue{}
+
+	knownIssuesLock.RLock()
+	for id, issue := range knownIssues {
+		if issue.Fields != nil && issue.Fields.Project.Key == *project.Key && InstanceOf(id) == project.config {
+			issues = append(issues, issue)
+		}
+	}
+	knownIssuesLock.RUnlock()
+
+	for _, issue := range issues {
+		key := issue.Key
+		name := IssuePageName(project, key)
+		title := IssuePageTitle(project, issue)
+
+		err := movePage(PageFilePath("jira", key), PageFilePath("jira", name), func(contents []byte) []byte {
+			oldTitle := ""
+			if m := legacyTitleMatcher.FindSubmatch(contents); m != nil {
+				oldTitle = string(m[2])
+			}
+			contents = legacyTitleMatcher.ReplaceAll(contents, []byte("${1}"+strings.ReplaceAll(title, "$", "$$")))
+			if oldTitle != "" && oldTitle != key && oldTitle != title {
+				contents = legacyAliasMatcher.ReplaceAll(contents, []byte("${1}${2}, [["+strings.ReplaceAll(oldTitle, "$", "$$")+"]]"))
+			}
+			return contents
+		})
+		if err != nil {
+			return errors.Wrap(err, "Failed to move page of "+key)
+		}
+
+		if ObsidianEnabled() {
+			err = movePage(obsidianPagePath("jira", key), obsidianPagePath("jira", name), nil)
+			if err != nil {
+				return errors.Wrap(err, "Failed to move Obsidian page of "+key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// movePage moves a file unless the destination already exists, rewriting its contents on the way if given a function
+func movePage(from string, to string, rewrite func([]byte) []byte) error {
+
+	if from == to {
+		return nil
+	}
+
+	exists, err := FileExists(from)
+	if err != nil || !exists {
+		return err
+	}
+
+	exists, err = FileExists(to)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		contents, err := ReadFile(from)
+		if err != nil {
+			return errors.Wrap(err, "Failed to read "+from)
+		}
+
+		if rewrite != nil {
+			contents = rewrite(contents)
+		}
+
+		err = WriteFile(to, contents)
+		if err != nil {
+			return errors.Wrap(err, "Failed to write "+to)
+		}
+	}
+
+	return RemoveFile(from)
+}
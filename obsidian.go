@@ -0,0 +1,442 @@
+package main
+
+import (
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	obsidianPlanningMatcher = regexp.MustCompile(`^(SCHEDULED|DEADLINE): <([0-9]{4}-[0-9]{2}-[0-9]{2})[^>]*>$`)
+	obsidianMarkerMatcher   = regexp.MustCompile(`^(TODO|DOING|DONE|LATER|NOW|WAIT|WAITING|IN-PROGRESS|CANCELED|CANCELLED) `)
+	obsidianImage           = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)(?:\{([^}]*)\})?`)
+	obsidianAttachment      = regexp.MustCompile(`^(?:\.\./)*assets/(?:[^)]*/)?([^/)]+)$`)
+	obsidianTagRef          = regexp.MustCompile(`#\[\[([^\[\]]+)\]\]`)
+	obsidianBlockRef        = regexp.MustCompile(`\(\(([0-9a-f-]{36})\)\)`)
+	obsidianAdmonition      = regexp.MustCompile(`^#\+BEGIN_([A-Z]+)$`)
+	obsidianBoldLine        = regexp.MustCompile(`^\*\*(.+)\*\*$`)
+	obsidianPipedLink       = regexp.MustCompile(`\[\[([^\[\]|]+?) \| ([^\[\]]+?)\]\]`)
+	obsidianPageEmbed       = regexp.MustCompile(`\{\{embed \[\[([^\]]+)\]\]\}\}`)
+	obsidianPageRef         = regexp.MustCompile(`\[\[([^\[\]]+)\]\]`)
+)
+
+// Logseq task markers and the Obsidian checkbox closest to each
+var obsidianCheckboxes = map[string]string{
+	"TODO":        "[ ]",
+	"LATER":       "[ ]",
+	"WAIT":        "[ ]",
+	"WAITING":     "[ ]",
+	"DOING":       "[/]",
+	"NOW":         "[/]",
+	"IN-PROGRESS": "[/]",
+	"DONE":        "[x]",
+	"CANCELED":    "[-]",
+	"CANCELLED":   "[-]",
+}
+
+// Logseq-only properties with no meaning in Obsidian
+var obsidianDroppedProperties = []string{
+	"collapsed",
+	"heading",
+	"logseq.order-list-type",
+}
+
+// ObsidianEnabled returns whether pages should also be written to an Obsidian vault
+func ObsidianEnabled() bool {
+	o := config.Jira.Options.Outputs.Obsidian
+	return o.Enabled != nil && *o.Enabled && o.VaultRoot != nil
+}
+
+// obsidianFolder returns the vault folder for a Logseq pages directory, e.g. "calendar/Outlook" to "Calendar/Outlook"
+func obsidianFolder(dir string) string {
+	o := config.Jira.Options.Outputs.Obsidian
+
+	segments := strings.Split(dir, "/")
+
+	folder := segments[0]
+	switch segments[0] {
+	case "jira":
+		folder = "Jira"
+		if o.Folders.Jira != nil {
+			folder = *o.Folders.Jira
+		}
+	case "calendar":
+		folder = "Calendar"
+		if o.Folders.Calendar != nil {
+			folder = *o.Folders.Calendar
+		}
+	}
+
+	return path.Join(append([]string{folder}, segments[1:]...)...)
+}
+
+func obsidianAssetFolder() string {
+	o := config.Jira.Options.Outputs.Obsidian
+	if o.Folders.Assets != nil {
+		return *o.Folders.Assets
+	}
+	return "Attachments"
+}
+
+// WriteObsidianPage writes a page given in Logseq Markdown into the vault.
+// Namespaces become folders rather than being encoded into the file name.
+func WriteObsidianPage(dir string, title string, contents []byte) error {
+
+	return WriteFile(obsidianPagePath(dir, title), MarkdownToObsidian(contents))
+}
+
+// obsidianPagePath returns where WriteObsidianPage writes a page
+func obsidianPagePath(dir string, title string) string {
+
+	name := title
+	if strings.HasPrefix(strings.ToLower(name), strings.ToLower(dir)+"/") {
+		name = name[len(dir)+1:]
+	}
+
+	segments := strings.Split(name, "/")
+	for i, s := range segments {
+		segments[i] = obsidianFileName(s)
+	}
+
+	return path.Join(append([]string{*config.Jira.Options.Outputs.Obsidian.VaultRoot, obsidianFolder(dir)}, segments...)...) + ".md"
+}
+
+// WriteObsidianAttachment copies an attachment already saved into the Logseq graph into the vault
+func WriteObsidianAttachment(logseqFilePath string) error {
+
+	filePath := path.Join(*config.Jira.Options.Outputs.Obsidian.VaultRoot, obsidianAssetFolder(), filepath.Base(logseqFilePath))
+
+	exists, err := FileExists(filePath)
+	if err != nil || exists {
+		return err
+	}
+
+	contents, err := ReadFile(logseqFilePath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to read attachment "+logseqFilePath)
+	}
+
+	return WriteFile(filePath, contents)
+}
+
+// Characters Obsidian won't allow in file names
+func obsidianFileName(name string) string {
+	return strings.NewReplacer(
+		`\`, "＼",
+		`:`, "：",
+		`*`, "＊",
+		`?`, "？",
+		`"`, "＂",
+		`<`, "＜",
+		`>`, "＞",
+		`|`, "｜",
+		`#`, "＃",
+		`^`, "＾",
+		`[`, "［",
+		`]`, "］",
+	).Replace(name)
+}
+
+// MarkdownToObsidian converts a page written in Logseq's Markdown flavour for an Obsidian vault:
+// page properties become YAML frontmatter, task markers become checkboxes with Tasks plugin dates,
+// `id::` properties become ^block-ids and attachments become embeds.
+func MarkdownToObsidian(markdown []byte) []byte {
+
+	page := ParseLogseqPage(string(markdown))
+
+	output := obsidianFrontmatter(page.Properties)
+
+	for _, line := range page.Prelude {
+		output = append(output, obsidianInline(line))
+	}
+
+	for _, b := range page.Blocks {
+		output = append(output, obsidianBlock(b, "")...)
+	}
+
+	return []byte(strings.Join(output, "\n"))
+}
+
+// obsidianBlock renders a block and the blocks under it, each level indented by another tab
+func obsidianBlock(b *logseqBlock, indent string) (lines []string) {
+
+	marker := "- "
+	if b.ordered {
+		marker = "1. "
+	}
+
+	if m := obsidianAdmonition.FindStringSubmatch(b.lines[0]); m != nil {
+		callout, used := obsidianCallout(strings.ToLower(m[1]), b.lines[1:], indent+marker, indent+"  ")
+		lines = callout
+		if rest := b.lines[1+used:]; len(rest) > 0 {
+			lines = append(lines, "") // Or the lines after would continue the callout
+			lines = append(lines, obsidianContent(indent, rest, false)...)
+		}
+	} else {
+		lines = obsidianBlockLines(b, indent, marker)
+	}
+
+	for _, child := range b.children {
+		lines = append(lines, obsidianBlock(child, indent+"\t")...)
+	}
+
+	return
+}
+
+func obsidianBlockLines(b *logseqBlock, indent string, marker string) (lines []string) {
+
+	text := b.lines[0]
+	suffixes := []string{} // Appended to the first line, e.g. task dates
+	id := ""
+	properties := []Property{}
+	content := []string{}
+
+	for _, p := range b.planning {
+		if m := obsidianPlanningMatcher.FindStringSubmatch(p); m != nil {
+			if m[1] == "DEADLINE" {
+				suffixes = append(suffixes, "📅 "+m[2])
+			} else {
+				suffixes = append(suffixes, "⏳ "+m[2])
+			}
+		}
+	}
+
+	for _, d := range b.drawers {
+		if d.name != "AGENDA" {
+			content = append(content, ":"+d.name+":")
+			content = append(content, d.lines...)
+			content = append(content, ":END:")
+			continue
+		}
+		for _, l := range d.lines {
+			if k, v, ok := strings.Cut(l, ": "); ok {
+				suffixes = append(suffixes, "["+k+":: "+v+"]") // Dataview inline field
+			}
+		}
+	}
+
+	for _, p := range b.properties {
+		switch {
+		case p.Key == "id":
+			id = p.Value
+		case !slices.Contains(obsidianDroppedProperties, p.Key):
+			properties = append(properties, p)
+		}
+	}
+
+	inFence := markdownFence.MatchString(strings.TrimSpace(text))
+
+	if m := obsidianMarkerMatcher.FindStringSubmatch(text); m != nil {
+		text = obsidianCheckboxes[m[1]] + " " + strings.TrimPrefix(text, m[0])
+	}
+	if !inFence {
+		text = obsidianInline(text)
+	}
+
+	first := indent + marker + text
+	for _, s := range suffixes {
+		first += " " + s
+	}
+	if id != "" {
+		first += " ^" + id
+	}
+	lines = append(lines, first)
+
+	for _, p := range properties {
+		lines = append(lines, indent+"  "+p.Key+":: "+obsidianInline(p.Value))
+	}
+
+	return append(lines, obsidianContent(indent, append(content, b.lines[1:]...), inFence)...)
+}
+
+// obsidianContent renders the lines of a block after the first, leaving code as it is
+func obsidianContent(indent string, content []string, inFence bool) (lines []string) {
+
+	for i := 0; i < len(content); i++ {
+		l := content[i]
+		trimmed := strings.TrimSpace(l)
+		if markdownFence.MatchString(trimmed) {
+			inFence = !inFence
+		} else if m := obsidianAdmonition.FindStringSubmatch(trimmed); m != nil && !inFence {
+			callout, used := obsidianCallout(strings.ToLower(m[1]), content[i+1:], indent+"  ", indent+"  ")
+			lines = append(lines, callout...)
+			i += used
+			if i+1 < len(content) {
+				lines = append(lines, "") // Or the lines after would continue the callout
+			}
+			continue
+		} else if !inFence {
+			l = obsidianInline(l)
+		}
+		lines = append(lines, obsidianIndent(indent, l))
+	}
+
+	return
+}
+
+// obsidianCallout renders an admonition, like #+BEGIN_NOTE, as a callout, its bold first line as the title.
+// It's given the lines after #+BEGIN_ and returns how many of them it used, up to and including #+END_.
+func obsidianCallout(kind string, lines []string, first string, indent string) (callout []string, used int) {
+
+	content := []string{}
+	for _, l := range lines {
+		used++
+		if strings.HasPrefix(strings.TrimSpace(l), "#+END_") {
+			break
+		}
+		content = append(content, l)
+	}
+
+	title := ""
+	if len(content) > 0 {
+		if m := obsidianBoldLine.FindStringSubmatch(content[0]); m != nil {
+			title = " " + m[1]
+			content = content[1:]
+		}
+	}
+
+	callout = append(callout, first+"> [!"+kind+"]"+title)
+	inFence := false
+	for _, l := range content {
+		if markdownFence.MatchString(strings.TrimSpace(l)) {
+			inFence = !inFence
+		} else if !inFence {
+			l = obsidianInline(l)
+		}
+		callout = append(callout, strings.TrimRight(indent+"> "+l, " "))
+	}
+
+	return
+}
+
+// obsidianIndent puts a line of a block back under its bullet
+func obsidianIndent(indent string, line string) string {
+	if strings.TrimSpace(line) == "" {
+		return ""
+	}
+	return indent + "  " + line
+}
+
+func obsidianFrontmatter(properties []Property) []string {
+	if len(properties) == 0 {
+		return nil
+	}
+
+	lines := []string{"---"}
+	aliases := []string{}
+	tags := []string{}
+
+	for _, p := range properties {
+		switch p.Key {
+		case "alias":
+			for _, a := range strings.Split(p.Value, ",") {
+				aliases = append(aliases, strings.Trim(strings.TrimSpace(a), "[]"))
+			}
+		case "exclude-from-graph-view":
+			if p.Value == "true" {
+				tag := "jira"
+				if t := config.Jira.Options.Outputs.Obsidian.GraphExcludeTag; t != nil {
+					tag = *t
+				}
+				tags = append(tags, tag)
+			}
+		case "tags":
+			for _, t := range strings.Split(p.Value, ",") {
+				tags = append(tags, strings.Trim(strings.TrimSpace(t), "[]#"))
+			}
+		case "collapsed":
+		default:
+			lines = append(lines, p.Key+": "+obsidianYAMLValue(p.Value))
+		}
+	}
+
+	if len(aliases) > 0 {
+		lines = append(lines, "aliases: "+obsidianYAMLList(aliases))
+	}
+	if len(tags) > 0 {
+		lines = append(lines, "tags: "+obsidianYAMLList(tags))
+	}
+
+	return append(lines, "---", "")
+}
+
+// obsidianYAMLValue turns a property value into YAML, comma separated [[links]] become a list
+func obsidianYAMLValue(value string) string {
+	value = obsidianPipedLink.ReplaceAllString(value, "[[$1|$1 - $2]]")
+
+	parts := strings.Split(value, ", ")
+	if len(parts) > 1 {
+		allLinks := true
+		for _, p := range parts {
+			if !obsidianPageRef.MatchString(p) || obsidianPageRef.FindString(p) != p {
+				allLinks = false
+				break
+			}
+		}
+		if allLinks {
+			return obsidianYAMLList(parts)
+		}
+	}
+
+	return strconv.Quote(value)
+}
+
+func obsidianYAMLList(values []string) string {
+	for i, v := range values {
+		values[i] = strconv.Quote(v)
+	}
+	return "[" + strings.Join(values, ", ") + "]"
+}
+
+// obsidianInline converts Logseq-specific inline syntax, leaving code spans as they are
+func obsidianInline(text string) string {
+	parts := strings.Split(text, "`")
+	for i := range parts {
+		if i%2 == 0 || (i == len(parts)-1 && len(parts)%2 == 0) { // Outside code, or after a backtick left unclosed
+			parts[i] = obsidianInlineText(parts[i])
+		}
+	}
+	return strings.Join(parts, "`")
+}
+
+func obsidianInlineText(text string) string {
+	text = obsidianImage.ReplaceAllStringFunc(text, obsidianImageEmbed)
+	text = obsidianPageEmbed.ReplaceAllString(text, "![[$1]]")
+	text = obsidianTagRef.ReplaceAllString(text, "[[$1]]") // Obsidian tags can't hold spaces
+	text = obsidianBlockRef.ReplaceAllString(text, "[[#^$1]]")
+	text = obsidianPipedLink.ReplaceAllString(text, "[[$1|$1 - $2]]")
+	return text
+}
+
+// obsidianImageEmbed turns an image of an attachment into an embed, and a size into Obsidian's, e.g. ![[a.png|100]]
+func obsidianImageEmbed(image string) string {
+
+	m := obsidianImage.FindStringSubmatch(image)
+	node := markdownImageNode(m[1], m[2], m[3])
+
+	size := node.Params["width"]
+	if h := node.Params["height"]; h != "" {
+		if size == "" {
+			size = "0"
+		}
+		size += "x" + h
+	}
+
+	if a := obsidianAttachment.FindStringSubmatch(m[2]); a != nil {
+		if size != "" {
+			return "![[" + a[1] + "|" + size + "]]"
+		}
+		return "![[" + a[1] + "]]"
+	}
+
+	alt := m[1]
+	if size != "" {
+		alt += "|" + size
+	}
+	return "![" + alt + "](" + m[2] + ")"
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var orgPlainTag = regexp.MustCompile(`^[^\s#\[\],.!?;:"'\\]+$`)
+
+// MarkdownToOrg converts a page written in Logseq's Markdown flavour into
+// Logseq's Org flavour: page and block properties become :PROPERTIES: drawers,
+// blocks become * headings and fenced code becomes #+BEGIN_SRC blocks.
+func MarkdownToOrg(markdown []byte) []byte {
+
+	page := ParseLogseqPage(string(markdown))
+	p := &markdownParser{keep: true}
+
+	output := []string{}
+
+	if len(page.Properties) > 0 {
+		output = append(output, orgDrawer(page.Properties)...)
+		if len(page.Prelude) > 0 || len(page.Blocks) > 0 {
+			output = append(output, "")
+		}
+	}
+
+	output = append(output, orgBlocks(p.blocks(page.Prelude))...)
+
+	for _, b := range page.Blocks {
+		output = append(output, orgBlock(p, b, 1)...)
+	}
+
+	return []byte(strings.Join(output, "\n"))
+}
+
+// orgBlock renders a block as a heading, with what it holds and the blocks under it
+func orgBlock(p *markdownParser, b *logseqBlock, depth int) (lines []string) {
+
+	heading := strings.Repeat("*", depth)
+	properties := b.properties
+	content := p.blocks(b.lines)
+
+	if m := markdownHeading.FindStringSubmatch(b.lines[0]); m != nil {
+		heading += " " + orgInline(p.inline(m[2]))
+		properties = append([]Property{{"heading", strconv.Itoa(len(m[1]))}}, properties...)
+		content = p.blocks(b.lines[1:])
+	} else if title := p.blocks(b.lines[:1]); len(title) == 1 && title[0].Kind == WikiParagraph {
+		heading += " " + orgInline(title[0].Children)
+		content = p.blocks(b.lines[1:])
+	} else if len(properties) == 0 && len(b.planning) == 0 && len(b.drawers) == 0 && len(b.children) == 0 {
+		return orgBlocks(content) // Starting with a table, code or the like it can't be a heading, so it joins the one before
+	} else if len(content) > 0 {
+		heading += " " + orgUntitled(content[0]) // It needs a heading of its own for the rest, named for what it holds
+	}
+
+	lines = append(lines, heading)
+
+	if len(b.planning) > 0 {
+		lines = append(lines, strings.Join(b.planning, " "))
+	}
+
+	if len(properties) > 0 {
+		lines = append(lines, orgDrawer(properties)...)
+	}
+
+	for _, d := range b.drawers {
+		lines = append(lines, ":"+d.name+":")
+		lines = append(lines, d.lines...)
+		lines = append(lines, ":END:")
+	}
+
+	lines = append(lines, orgBlocks(content)...)
+
+	for _, child := range b.children {
+		lines = append(lines, orgBlock(p, child, depth+1)...)
+	}
+
+	return
+}
+
+// orgUntitled names a block for its heading by what it starts with, when that can't be the heading itself
+func orgUntitled(n *WikiNode) string {
+	switch n.Kind {
+	case WikiTable:
+		return "Table"
+	case WikiCode:
+		return "Code"
+	case WikiNoFormat:
+		return "Example"
+	case WikiQuote:
+		return "Quote"
+	case WikiPanel:
+		if title := n.Params["title"]; title != "" {
+			return title
+		}
+		name := n.Params["admonition"]
+		if name == "" {
+			name = wikiAdmonitions[n.Name]
+		}
+		if name == "" {
+			return "Note"
+		}
+		return name[:1] + strings.ToLower(name[1:])
+	case WikiList:
+		return "List"
+	case WikiRule:
+		return "Rule"
+	}
+	return "Block"
+}
+
+func orgDrawer(properties []Property) []string {
+	lines := []string{":PROPERTIES:"}
+	for _, p := range properties {
+		lines = append(lines, ":"+p.Key+": "+p.Value)
+	}
+	return append(lines, ":END:")
+}
+
+// orgBlocks renders parsed Markdown as Org, paragraphs one after another kept apart by a blank line
+func orgBlocks(nodes []*WikiNode) (lines []string) {
+	for i, n := range nodes {
+		if i > 0 && n.Kind == WikiParagraph && nodes[i-1].Kind == WikiParagraph {
+			lines = append(lines, "")
+		}
+		lines = append(lines, orgNode(n)...)
+	}
+	return
+}
+
+func orgNode(n *WikiNode) []string {
+
+	switch n.Kind {
+
+	case WikiParagraph:
+		return strings.Split(orgInline(n.Children), "\n")
+
+	case WikiHeading: // A heading would start a block of its own
+		return []string{"*" + orgInline(n.Children) + "*"}
+
+	case WikiCode:
+		lines := []string{strings.TrimSpace("#+BEGIN_SRC " + n.Params["language"])}
+		if n.Text != "" {
+			lines = append(lines, strings.Split(n.Text, "\n")...)
+		}
+		return append(lines, "#+END_SRC")
+
+	case WikiNoFormat:
+		return append(append([]string{"#+BEGIN_EXAMPLE"}, strings.Split(n.Text, "\n")...), "#+END_EXAMPLE")
+
+	case WikiQuote:
+		return append(append([]string{"#+BEGIN_QUOTE"}, orgBlocks(n.Children)...), "#+END_QUOTE")
+
+	case WikiPanel:
+		name := n.Params["admonition"]
+		if name == "" {
+			name = wikiAdmonitions[n.Name]
+		}
+		if name == "" {
+			name = "NOTE"
+		}
+		lines := []string{"#+BEGIN_" + name}
+		if title := n.Params["title"]; title != "" {
+			lines = append(lines, "*"+title+"*")
+		}
+		lines = append(lines, orgBlocks(n.Children)...)
+		return append(lines, "#+END_"+name)
+
+	case WikiList:
+		return orgList(n, "")
+
+	case WikiTable:
+		return orgTable(n)
+
+	case WikiRule:
+		return []string{"-----"}
+	}
+
+	return []string{orgInline([]*WikiNode{n})}
+}
+
+// orgList renders a list, each level under the one before indented by two spaces
+func orgList(list *WikiNode, indent string) (lines []string) {
+	for i, item := range list.Children {
+		marker := "- "
+		if list.Ordered {
+			marker = strconv.Itoa(i+1) + ". "
+		}
+		prefix := indent + marker
+		for _, child := range item.Children {
+			if child.Kind == WikiList {
+				lines = append(lines, orgList(child, indent+"  ")...)
+				continue
+			}
+			for _, line := range orgNode(child) {
+				lines = append(lines, prefix+line)
+				prefix = indent + strings.Repeat(" ", len(marker))
+			}
+		}
+		if prefix == indent+marker { // Nothing in it
+			lines = append(lines, strings.TrimRight(prefix, " "))
+		}
+	}
+	return
+}
+
+// orgTable renders a table, a rule under the first row if it's a header
+func orgTable(table *WikiNode) (lines []string) {
+	for i, row := range table.Children {
+		cells := []string{}
+		for _, cell := range row.Children {
+			text := []string{}
+			for _, child := range cell.Children {
+				text = append(text, orgNode(child)...)
+			}
+			cells = append(cells, strings.ReplaceAll(strings.Join(text, " "), "|", `\vert{}`))
+		}
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+		if i == 0 && wikiHeaderRow(row) {
+			rules := []string{}
+			for _, cell := range cells {
+				rules = append(rules, strings.Repeat("-", len(cell)+2))
+			}
+			lines = append(lines, "|"+strings.Join(rules, "+")+"|")
+		}
+	}
+	return
+}
+
+// orgInline renders inline formatting as Org
+func orgInline(nodes []*WikiNode) string {
+
+	b := strings.Builder{}
+
+	for _, n := range nodes {
+		switch n.Kind {
+		case WikiText, WikiMention, WikiMacro:
+			b.WriteString(n.Text)
+		case WikiEscape:
+			b.WriteString(`\` + n.Text)
+		case WikiBreak:
+			b.WriteString("\n")
+		case WikiStrong:
+			b.WriteString("*" + orgInline(n.Children) + "*")
+		case WikiEmphasis, WikiCitation:
+			b.WriteString("/" + orgInline(n.Children) + "/")
+		case WikiDeleted:
+			b.WriteString("+" + orgInline(n.Children) + "+")
+		case WikiInserted:
+			b.WriteString("_" + orgInline(n.Children) + "_")
+		case WikiSuperscript:
+			b.WriteString("^{" + orgInline(n.Children) + "}")
+		case WikiSubscript:
+			b.WriteString("_{" + orgInline(n.Children) + "}")
+		case WikiColor:
+			b.WriteString(orgInline(n.Children))
+		case WikiMonospace:
+			b.WriteString("~" + n.Text + "~")
+		case WikiLink, WikiAttachment, WikiPageRef:
+			label := orgInline(n.Children)
+			switch {
+			case n.Kind == WikiPageRef && n.Name == "#" && orgPlainTag.MatchString(n.Text):
+				b.WriteString("#" + n.Text)
+			case n.Kind == WikiPageRef && n.Name == "#":
+				b.WriteString("#[[" + n.Text + "]]")
+			case label == "" || label == n.Text:
+				b.WriteString("[[" + n.Text + "]]")
+			default:
+				b.WriteString("[[" + n.Text + "][" + label + "]]")
+			}
+		case WikiImage:
+			b.WriteString("[[" + n.Text + "]]")
+		case WikiBlockRef:
+			b.WriteString("((" + n.Text + "))")
+		}
+	}
+
+	return b.String()
+}
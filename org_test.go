@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// logseqFixtures returns the Logseq pages in testdata, for each conversion to compare with its own output
+func logseqFixtures(t *testing.T) []string {
+	t.Helper()
+	inputs, err := filepath.Glob(filepath.Join("testdata", "logseq", "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no Logseq fixtures found")
+	}
+	return inputs
+}
+
+func TestMarkdownToOrg(t *testing.T) {
+	for _, input := range logseqFixtures(t) {
+		name := strings.TrimSuffix(filepath.Base(input), ".md")
+		t.Run(name, func(t *testing.T) {
+			markdown, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden(t, filepath.Join("testdata", "org", name+".org"), string(MarkdownToOrg(markdown)))
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MagicalTux/natsort"
+	jira "github.com/andygrunwald/go-jira/v2/cloud"
+	"github.com/pkg/errors"
+)
+
+//go:embed templates/overview.md.tmpl
+var defaultOverviewTemplate string
+
+// ProjectOverview is the data model handed to project overview templates
+type ProjectOverview struct {
+	Project  string // Project key
+	Instance string // Instance base URL
+	Title    string // Overview page name
+
+	Total int
+	Open  int // Issues not closed
+
+	ByStatus   []OverviewCount // By status-simple
+	ByType     []OverviewCount // By jira-type
+	ByAssignee []OverviewCount // By assignee, "Unassigned" for none
+
+	// Lists only hold open issues, except RecentlyUpdated
+	Overdue         []OverviewIssue // Due before today
+	DueIn7Days      []OverviewIssue // Due today up to 7 days from now
+	DueIn14Days     []OverviewIssue // Due 8 to 14 days from now
+	Unassigned      []OverviewIssue
+	RecentlyUpdated []OverviewIssue // Updated within recent_days, most recent first
+	RecentDays      int
+
+	Options *JiraOptions // Options in effect for this project
+}
+
+type OverviewCount struct {
+	Name  string
+	Count int
+}
+
+type OverviewIssue struct {
+	Key          string
+	Title        string // "KEY | summary", resolves to the issue's page
+	StatusSimple string
+	Type         string
+	Assignee     string // Empty if unassigned
+	DueDate      *time.Time
+	Updated      time.Time
+}
+
+// ClosedMarkers returns the markers counted as closed, for queries to leave out as the lists do
+func (o *ProjectOverview) ClosedMarkers() []string {
+	return closedMarkers
+}
+
+// Property returns the name a default property is emitted under, for use in queries
+func (o *ProjectOverview) Property(key string) string {
+	name, _ := o.Options.Properties.Name(key)
+	return name
+}
+
+// WriteOverviewPages writes an overview page for every project that has them enabled, from knownIssues
+func WriteOverviewPages() error {
+	for _, c := range config.Jira.Instances {
+		if c.client == nil { // Disabled
+			continue
+		}
+		for _, project := range c.Projects {
+			o := project.Options.Outputs.Overview
+			if o.Enabled == nil || !*o.Enabled || !*project.Options.Outputs.Logseq.Enabled || PageCollision(project) != nil {
+				continue
+			}
+
+			overview, err := NewProjectOverview(project)
+			if err != nil {
+				return errors.Wrap(err, "Failed to build overview of "+*project.Key)
+			}
+
+			t, err := LoadTemplate(o.Template, "overview", defaultOverviewTemplate)
+			if err != nil {
+				return err
+			}
+
+			buf := &bytes.Buffer{}
+			err = t.Execute(buf, overview)
+			if err != nil {
+				return errors.Wrap(err, "Failed to execute overview template for "+*project.Key)
+			}
+
+			err = WritePage(overview.Title, bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+			if err != nil {
+				return errors.Wrap(err, "Failed to write overview of "+*project.Key)
+			}
+		}
+	}
+	return nil
+}
+
+// NewProjectOverview gathers the known issues of a project
+func NewProjectOverview(project *JiraProject) (*ProjectOverview, error) {
+
+	c := project.config
+	o := project.Options.Outputs.Overview
+
+	overview := &ProjectOverview{
+		Project:    *project.Key,
+		Instance:   *c.Connection.BaseURL,
+		Title:      ExpandPageName(project, "Jira/{project} Overview"),
+		RecentDays: 7,
+		Options:    &project.Options,
+	}
+	if o.Page != nil && *o.Page != "" {
+		overview.Title = ExpandPageName(project, *o.Page)
+	}
+	if o.RecentDays != nil {
+		overview.RecentDays = *o.RecentDays
+	}
+
+	issues := []*jira.Issue{}
+
+	knownIssuesLock.RLock()
+	for id, issue := range knownIssues {
+		if issue.Fields != nil && issue.Fields.Project.Key == *project.Key && InstanceOf(id) == c {
+			issues = append(issues, issue)
+		}
+	}
+	knownIssuesLock.RUnlock()
+
+	slices.SortFunc(issues, func(a, b *jira.Issue) int {
+		if natsort.Compare(a.Key, b.Key) {
+			return -1
+		}
+		return 1
+	})
+
+	now := startTime
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	recent := now.AddDate(0, 0, -overview.RecentDays)
+
+	byStatus := map[string]int{}
+	byType := map[string]int{}
+	byAssignee := map[string]int{}
+
+	for _, issue := range issues {
+
+		item, err := NewOverviewIssue(project, issue)
+		if err != nil {
+			return nil, err
+		}
+		due := item.DueDate
+
+		overview.Total += 1
+		byStatus[item.StatusSimple] += 1
+		byType[item.Type] += 1
+		if item.Assignee == "" {
+			byAssignee["Unassigned"] += 1
+		} else {
+			byAssignee[item.Assignee] += 1
+		}
+
+		if item.Updated.After(recent) {
+			overview.RecentlyUpdated = append(overview.RecentlyUpdated, item)
+		}
+
+		if ClosedMarker(item.StatusSimple) {
+			continue
+		}
+
+		overview.Open += 1
+
+		if item.Assignee == "" {
+			overview.Unassigned = append(overview.Unassigned, item)
+		}
+
+		if due != nil {
+			days := int(due.Sub(today).Hours() / 24)
+			switch {
+			case days < 0:
+				overview.Overdue = append(overview.Overdue, item)
+			case days < 7:
+				overview.DueIn7Days = append(overview.DueIn7Days, item)
+			case days < 14:
+				overview.DueIn14Days = append(overview.DueIn14Days, item)
+			}
+		}
+	}
+
+	byDue := func(a, b OverviewIssue) int {
+		return a.DueDate.Compare(*b.DueDate)
+	}
+	slices.SortStableFunc(overview.Overdue, byDue)
+	slices.SortStableFunc(overview.DueIn7Days, byDue)
+	slices.SortStableFunc(overview.DueIn14Days, byDue)
+	slices.SortStableFunc(overview.RecentlyUpdated, func(a, b OverviewIssue) int {
+		return b.Updated.Compare(a.Updated)
+	})
+
+	if o.Limit != nil && *o.Limit > 0 {
+		for _, list := range []*[]OverviewIssue{&overview.Overdue, &overview.DueIn7Days, &overview.DueIn14Days, &overview.Unassigned, &overview.RecentlyUpdated} {
+			*list = (*list)[:min(len(*list), *o.Limit)]
+		}
+	}
+
+	overview.ByStatus = overviewCounts(byStatus)
+	overview.ByType = overviewCounts(byType)
+	overview.ByAssignee = overviewCounts(byAssignee)
+
+	return overview, nil
+}
+
+// NewOverviewIssue summarises a known issue for listing on a generated page
+func NewOverviewIssue(project *JiraProject, issue *jira.Issue) (item OverviewIssue, err error) {
+
+	item = OverviewIssue{
+		Key:          issue.Key,
+		Title:        LogseqTitle(project.config, issue),
+		StatusSimple: SimplifyStatus(project, issue),
+		Type:         JiraTypeSubstitute(project, issue),
+		Updated:      time.Time(issue.Fields.Updated),
+	}
+
+	if issue.Fields.Assignee != nil {
+		item.Assignee = ProcessPersonName(issue.Fields.Assignee, project)
+	}
+
+	item.DueDate, err = GetDueDate(issue, project)
+	if err != nil {
+		return item, errors.Wrap(err, "Failed in GetDueDate on "+issue.Key)
+	}
+
+	return item, nil
+}
+
+// overviewCounts sorts counts largest first, then by name
+func overviewCounts(counts map[string]int) (output []OverviewCount) {
+	for name, count := range counts {
+		output = append(output, OverviewCount{name, count})
+	}
+	slices.SortFunc(output, func(a, b OverviewCount) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return
+}
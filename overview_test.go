@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// Queries on the overview must leave out every closed marker, as its lists do
+func TestOverviewQueriesLeaveOutClosed(t *testing.T) {
+
+	tmpl, err := LoadTemplate(nil, "overview", defaultOverviewTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, &ProjectOverview{Project: "PROJ", Options: &JiraOptions{}}); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	for _, marker := range closedMarkers {
+		if n := strings.Count(output, `(page-property ?p :status-simple "`+marker+`")`); n != 2 {
+			t.Errorf("%d advanced queries leave out %s, expected 2", n, marker)
+		}
+		if !strings.Contains(output, `(not (property :status-simple "`+marker+`"))`) {
+			t.Errorf("unassigned query doesn't leave out %s", marker)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira/v2/cloud"
+	"github.com/pkg/errors"
+)
+
+//go:embed templates/person.md.tmpl
+var defaultPersonTemplate string
+
+// Marks pages written by WritePersonPages, so they can be told apart from the user's own
+var personPageMatcher = regexp.MustCompile(`(?m)^:?[A-Za-z0-9_.\-]+::? jira-person$`)
+
+// PersonPage is the data model handed to person page templates
+type PersonPage struct {
+	Name      string   // Page name, as linked from issue pages
+	AccountID string   // Empty if only ever seen as a watcher
+	Aliases   []string // Other display names the person goes by
+
+	Assigned []PersonIssueGroup // By status-simple, alphabetically with DONE last
+	Reported []PersonIssueGroup
+	Watching []PersonIssueGroup
+
+	OpenAssigned int // Assigned issues not DONE
+
+	Options *JiraOptions // Top level options
+}
+
+type PersonIssueGroup struct {
+	Status string
+	Issues []OverviewIssue // Sorted by due date, issues without one last
+}
+
+// Property returns the name a default property is emitted under
+func (p *PersonPage) Property(key string) string {
+	name, _ := p.Options.Properties.Name(key)
+	return name
+}
+
+// person collects everything known about someone while building their page
+type person struct {
+	accountID string
+	names     map[string]int // Page names seen on issues, by count
+	aliases   []string       // Display names, as seen or configured
+	assigned  []OverviewIssue
+	reported  []OverviewIssue
+	watching  []OverviewIssue
+}
+
+func (p *person) addName(displayName string, seen bool) {
+	if seen {
+		p.names[PersonName(displayName)] += 1
+	}
+	if !slices.Contains(p.aliases, displayName) {
+		p.aliases = append(p.aliases, displayName)
+	}
+}
+
+// pageName is the name seen most often on issues, so links resolve, or else a configured one
+func (p *person) pageName() string {
+	best, bestCount := "", 0
+	for name, count := range p.names {
+		if count > bestCount || (count == bestCount && name < best) {
+			best, bestCount = name, count
+		}
+	}
+	if best == "" && len(p.aliases) > 0 {
+		best = PersonName(p.aliases[0])
+	}
+	return best
+}
+
+// WritePersonPages writes a page per person from config.Jira.Users and the users seen on known issues,
+// listing their assigned, reported and watched issues across every instance
+func WritePersonPages() error {
+
+	o := config.Jira.Options.Outputs.People
+	if o.Enabled == nil || !*o.Enabled || !*config.Jira.Options.Outputs.Logseq.Enabled {
+		return nil
+	}
+
+	people := map[string]*person{} // By account ID, or "name:" and the page name for watchers
+
+	lookup := func(accountID string) *person {
+		p, ok := people[accountID]
+		if !ok {
+			p = &person{accountID: accountID, names: map[string]int{}}
+			people[accountID] = p
+		}
+		return p
+	}
+
+	for _, u := range config.Jira.Users {
+		lookup(u.AccountID).addName(u.DisplayName, false)
+	}
+
+	knownIssuesLock.RLock()
+	ids := make([]string, 0, len(knownIssues))
+	issues := make(map[string]*jira.Issue, len(knownIssues))
+	for id, issue := range knownIssues {
+		ids = append(ids, id)
+		issues[id] = issue
+	}
+	knownIssuesLock.RUnlock()
+
+	slices.Sort(ids)
+
+	watched := map[string][]string{} // Watcher names by issue, matched up to people once every account is known
+	items := map[string]OverviewIssue{}
+
+	for _, id := range ids {
+		issue := issues[id]
+
+		project := ProjectOf(id, issue)
+		if project == nil || project.config.client == nil {
+			continue
+		}
+
+		item, err := NewOverviewIssue(project, issue)
+		if err != nil {
+			return err
+		}
+		items[id] = item
+
+		if u := issue.Fields.Assignee; u != nil && u.AccountID != "" {
+			p := lookup(u.AccountID)
+			p.addName(u.DisplayName, true)
+			p.assigned = append(p.assigned, item)
+		}
+
+		if u := issue.Fields.Reporter; u != nil && u.AccountID != "" {
+			p := lookup(u.AccountID)
+			p.addName(u.DisplayName, true)
+			p.reported = append(p.reported, item)
+		}
+
+		if *project.Options.Outputs.Logseq.IncludeWatchers && issue.Fields.Watches != nil && issue.Fields.Watches.WatchCount > 0 {
+			watchers := &[]string{}
+			err = GetWatchers(project, issue, watchers)
+			if err != nil {
+				return errors.Wrap(err, "Failed in GetWatchers for "+issue.Key)
+			}
+			watched[id] = *watchers
+		}
+	}
+
+	for _, id := range ids {
+		for _, w := range watched[id] {
+			name := strings.TrimSuffix(strings.TrimPrefix(w, "[["), "]]")
+
+			var p *person
+			for _, k := range sortedPeople(people) {
+				if people[k].names[name] > 0 || slices.Contains(people[k].aliases, name) {
+					p = people[k]
+					break
+				}
+			}
+			if p == nil {
+				p = lookup("name:" + name)
+				p.addName(name, true)
+			}
+
+			p.watching = append(p.watching, items[id])
+		}
+	}
+
+	t, err := LoadTemplate(o.Template, "person", defaultPersonTemplate)
+	if err != nil {
+		return err
+	}
+
+	written := map[string]bool{}
+
+	for _, k := range sortedPeople(people) {
+		p := people[k]
+
+		page := &PersonPage{
+			Name:     p.pageName(),
+			Assigned: groupByStatus(p.assigned),
+			Reported: groupByStatus(p.reported),
+			Watching: groupByStatus(p.watching),
+			Options:  &config.Jira.Options,
+		}
+
+		if page.Name == "" || written[page.Name] {
+			continue
+		}
+		written[page.Name] = true
+
+		if !strings.HasPrefix(k, "name:") {
+			page.AccountID = k
+		}
+
+		for _, a := range p.aliases {
+			if a != page.Name && !slices.Contains(page.Aliases, a) {
+				page.Aliases = append(page.Aliases, a)
+			}
+		}
+		for name := range p.names {
+			if name != page.Name && !slices.Contains(page.Aliases, name) {
+				page.Aliases = append(page.Aliases, name)
+			}
+		}
+		slices.Sort(page.Aliases)
+
+		for _, i := range p.assigned {
+			if !ClosedMarker(i.StatusSimple) {
+				page.OpenAssigned += 1
+			}
+		}
+
+		ours, err := ownPersonPage(page.Name)
+		if err != nil {
+			return err
+		}
+		if !ours {
+			slog.Warn("Not writing person page for " + page.Name + ", a page by that name already exists")
+			continue
+		}
+
+		buf := &bytes.Buffer{}
+		err = t.Execute(buf, page)
+		if err != nil {
+			return errors.Wrap(err, "Failed to execute person template for "+page.Name)
+		}
+
+		err = WritePage(page.Name, bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+		if err != nil {
+			return errors.Wrap(err, "Failed to write person page for "+page.Name)
+		}
+	}
+
+	return nil
+}
+
+func sortedPeople(people map[string]*person) []string {
+	keys := make([]string, 0, len(people))
+	for k := range people {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// ownPersonPage returns whether a person page can be written, that is it doesn't exist or was written by us
+func ownPersonPage(name string) (bool, error) {
+	for _, dir := range []string{"", "jira"} { // The user's own pages live directly in pages/
+		filePath := PageFilePath(dir, name)
+
+		exists, err := FileExists(filePath)
+		if err != nil {
+			return false, errors.Wrap(err, "Failed to check for page "+name)
+		}
+		if !exists {
+			continue
+		}
+		if dir == "" {
+			return false, nil
+		}
+
+		contents, err := ReadFile(filePath)
+		if err != nil {
+			return false, errors.Wrap(err, "Failed to read page "+name)
+		}
+		if !personPageMatcher.Match(contents) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// groupByStatus groups issues by status-simple, sorting each group by due date
+func groupByStatus(items []OverviewIssue) (groups []PersonIssueGroup) {
+
+	for _, item := range items {
+		i := slices.IndexFunc(groups, func(g PersonIssueGroup) bool { return g.Status == item.StatusSimple })
+		if i < 0 {
+			groups = append(groups, PersonIssueGroup{Status: item.StatusSimple})
+			i = len(groups) - 1
+		}
+		groups[i].Issues = append(groups[i].Issues, item)
+	}
+
+	for _, g := range groups {
+		slices.SortStableFunc(g.Issues, func(a, b OverviewIssue) int {
+			switch {
+			case a.DueDate == nil && b.DueDate == nil:
+				return 0
+			case a.DueDate == nil:
+				return 1
+			case b.DueDate == nil:
+				return -1
+			}
+			return a.DueDate.Compare(*b.DueDate)
+		})
+	}
+
+	slices.SortStableFunc(groups, func(a, b PersonIssueGroup) int { // DONE and CANCELED last, the rest alphabetically
+		if ClosedMarker(a.Status) != ClosedMarker(b.Status) {
+			if ClosedMarker(a.Status) {
+				return 1
+			}
+			return -1
+		}
+		return strings.Compare(a.Status, b.Status)
+	})
+
+	return
+}
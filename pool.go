@@ -0,0 +1,177 @@
+package main
+
+import (
+	"sync"
+)
+
+// WorkPool runs issue processing for every project of every instance on one
+// shared set of workers. Each instance has its own concurrency cap on top of
+// the global one, and projects are served round-robin so a single large
+// project can't starve the others.
+type WorkPool struct {
+	lock      sync.Mutex
+	limit     int // Global cap on running tasks
+	running   int
+	instances map[*JiraConfig]*poolInstance
+	queues    []*PoolQueue
+	next      int // Queue to look at first on the next dispatch, for fairness
+}
+
+type poolInstance struct {
+	limit   int // Per-instance cap on running tasks
+	running int
+}
+
+// PoolQueue holds the pending tasks of a single project
+type PoolQueue struct {
+	pool     *WorkPool
+	instance *poolInstance
+	tasks    []func() error
+	pending  int  // Queued plus running tasks
+	closed   bool // No more tasks will be added
+	finished bool
+	err      error
+	done     chan struct{}
+	onDone   func()
+}
+
+func NewWorkPool(limit int) *WorkPool {
+	if limit < 1 {
+		limit = 1
+	}
+	return &WorkPool{
+		limit:     limit,
+		instances: map[*JiraConfig]*poolInstance{},
+	}
+}
+
+// Queue registers a new queue for the given instance. onDone, if given, is
+// called once the queue has been closed and all of its tasks have finished.
+func (p *WorkPool) Queue(c *JiraConfig, onDone func()) *PoolQueue {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	instance, ok := p.instances[c]
+	if !ok {
+		limit := 4
+		if c.Connection.Parallel != nil {
+			limit = *c.Connection.Parallel
+		}
+		instance = &poolInstance{limit: max(limit, 1)}
+		p.instances[c] = instance
+	}
+
+	q := &PoolQueue{
+		pool:     p,
+		instance: instance,
+		done:     make(chan struct{}),
+		onDone:   onDone,
+	}
+
+	p.queues = append(p.queues, q)
+
+	return q
+}
+
+// Go adds a task to the queue
+func (q *PoolQueue) Go(f func() error) {
+	p := q.pool
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	q.tasks = append(q.tasks, f)
+	q.pending += 1
+	p.dispatch()
+}
+
+// Close marks that no more tasks will be added to the queue
+func (q *PoolQueue) Close() {
+	p := q.pool
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	q.closed = true
+	q.finishIfDone()
+}
+
+// Wait blocks until the queue is closed and drained, returning the first error from its tasks
+func (q *PoolQueue) Wait() error {
+	<-q.done
+	return q.err
+}
+
+// dispatch starts as many queued tasks as the caps allow, must be called with the lock held
+func (p *WorkPool) dispatch() {
+	for p.running < p.limit {
+		q := p.pick()
+		if q == nil {
+			return
+		}
+
+		f := q.tasks[0]
+		q.tasks[0] = nil
+		q.tasks = q.tasks[1:]
+
+		p.running += 1
+		q.instance.running += 1
+
+		go func() {
+			err := f()
+
+			p.lock.Lock()
+			defer p.lock.Unlock()
+
+			p.running -= 1
+			q.instance.running -= 1
+			q.pending -= 1
+			if err != nil && q.err == nil {
+				q.err = err
+			}
+			q.finishIfDone()
+			p.dispatch()
+		}()
+	}
+}
+
+// pick returns the next queue with a runnable task, round-robin, must be called with the lock held
+func (p *WorkPool) pick() *PoolQueue {
+	n := len(p.queues)
+	for i := 0; i < n; i++ {
+		idx := (p.next + i) % n
+		q := p.queues[idx]
+		if len(q.tasks) > 0 && q.instance.running < q.instance.limit {
+			p.next = (idx + 1) % n
+			return q
+		}
+	}
+	return nil
+}
+
+// finishIfDone must be called with the lock held
+func (q *PoolQueue) finishIfDone() {
+	if !q.closed || q.pending > 0 || q.finished {
+		return
+	}
+	q.finished = true
+
+	p := q.pool
+	for i, other := range p.queues {
+		if other == q {
+			p.queues = append(p.queues[:i], p.queues[i+1:]...)
+			if p.next > i {
+				p.next -= 1
+			}
+			break
+		}
+	}
+	if len(p.queues) > 0 {
+		p.next %= len(p.queues)
+	} else {
+		p.next = 0
+	}
+
+	if q.onDone != nil {
+		q.onDone()
+	}
+	close(q.done)
+}
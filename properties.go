@@ -0,0 +1,116 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// Property is a single `key:: value` pair
+type Property struct {
+	Key   string
+	Value string
+}
+
+func (p Property) String() string {
+	return p.Key + ":: " + p.Value
+}
+
+// Properties Logseq itself gives meaning to, these are never prefixed
+var reservedProperties = []string{
+	"alias",
+	"title",
+	"id",
+	"tags",
+	"icon",
+	"public",
+	"filters",
+	"collapsed",
+	"exclude-from-graph-view",
+	"logseq.order-list-type",
+	"query-table",
+	"query-properties",
+	"query-sort-by",
+	"query-sort-desc",
+}
+
+type PropertyOptions struct {
+	Prefix *string `json:"prefix"` // Prepended to every emitted property name not already carrying it, except those Logseq reserves (alias, title, id, ...)
+	Map    []struct {
+		From *string `json:"from"` // Property name as emitted by default
+		To   *string `json:"to"`   // Name to emit instead, used as-is without the prefix
+		Drop *bool   `json:"drop"` // Whether to leave this property out entirely
+	} `json:"map"`
+	Order []*string `json:"order"` // Default property names to emit first, in this order, the rest follow as usual
+}
+
+// Name returns the name a default property should be emitted under, and whether it should be emitted at all
+func (o *PropertyOptions) Name(key string) (string, bool) {
+	if o == nil {
+		return key, true
+	}
+
+	for _, m := range o.Map {
+		if m.From == nil || *m.From != key {
+			continue
+		}
+		if m.Drop != nil && *m.Drop {
+			return "", false
+		}
+		if m.To != nil {
+			return *m.To, true
+		}
+	}
+
+	if o.Prefix != nil && !strings.HasPrefix(key, *o.Prefix) && !slices.Contains(reservedProperties, key) {
+		return *o.Prefix + key, true
+	}
+
+	return key, true
+}
+
+// Apply renames, drops and reorders a list of properties
+func (o *PropertyOptions) Apply(properties []Property) []Property {
+	if o == nil {
+		return properties
+	}
+
+	ordered := properties
+
+	if len(o.Order) > 0 {
+		ordered = make([]Property, 0, len(properties))
+		used := make([]bool, len(properties))
+		for _, key := range o.Order {
+			for i, p := range properties {
+				if !used[i] && key != nil && p.Key == *key {
+					ordered = append(ordered, p)
+					used[i] = true
+				}
+			}
+		}
+		for i, p := range properties {
+			if !used[i] {
+				ordered = append(ordered, p)
+			}
+		}
+	}
+
+	output := make([]Property, 0, len(ordered))
+
+	for _, p := range ordered {
+		name, ok := o.Name(p.Key)
+		if !ok {
+			continue
+		}
+		output = append(output, Property{name, p.Value})
+	}
+
+	return output
+}
+
+// PrefixProperties renders properties as lines, each with the given prefix
+func PrefixProperties(properties []Property, prefix string) (lines []string) {
+	for _, p := range properties {
+		lines = append(lines, prefix+p.String())
+	}
+	return
+}
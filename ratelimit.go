@@ -0,0 +1,237 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimiter is a token bucket shared by every call made against a single
+// Jira instance. On top of the steady-state budget it reads the rate limiting
+// headers Jira returns, so we can pause before hitting a 429 rather than after.
+type RateLimiter struct {
+	name string
+
+	lock        sync.Mutex
+	rate        float64 // Tokens added per second, <= 0 means unlimited
+	burst       float64 // Maximum number of tokens held
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time // Set from response headers, no calls go out before this
+
+	maxRetries  int
+	backoffBase time.Duration
+	backoffMax  time.Duration
+
+	throttledNanos atomic.Int64 // Total time spent waiting on the limiter
+	throttleCount  atomic.Int64 // Number of waits
+	retryCount     atomic.Int64 // Number of retried calls
+}
+
+func NewRateLimiter(c *JiraConfig) *RateLimiter {
+	r := &RateLimiter{
+		name:        *c.Connection.BaseURL,
+		rate:        10,
+		burst:       10,
+		maxRetries:  5,
+		backoffBase: time.Second,
+		backoffMax:  time.Minute * 2,
+		last:        time.Now(),
+	}
+
+	l := c.Connection.RateLimit
+
+	if l.RequestsPerSecond != nil {
+		r.rate = *l.RequestsPerSecond
+	}
+	if l.Burst != nil {
+		r.burst = float64(*l.Burst)
+	} else if r.rate > 0 {
+		r.burst = math.Max(1, math.Ceil(r.rate))
+	}
+	if l.MaxRetries != nil {
+		r.maxRetries = *l.MaxRetries
+	}
+	if l.BackoffBaseMs != nil {
+		r.backoffBase = time.Duration(*l.BackoffBaseMs) * time.Millisecond
+	}
+	if l.BackoffMaxMs != nil {
+		r.backoffMax = time.Duration(*l.BackoffMaxMs) * time.Millisecond
+	}
+
+	r.tokens = r.burst
+
+	return r
+}
+
+// Wait blocks until a call may be made
+func (r *RateLimiter) Wait() {
+	for {
+		r.lock.Lock()
+		now := time.Now()
+
+		var delay time.Duration
+
+		if now.Before(r.pausedUntil) {
+			delay = r.pausedUntil.Sub(now)
+		} else if r.rate <= 0 {
+			r.lock.Unlock()
+			return
+		} else {
+			r.tokens = math.Min(r.burst, r.tokens+now.Sub(r.last).Seconds()*r.rate)
+			r.last = now
+			if r.tokens >= 1 {
+				r.tokens -= 1
+				r.lock.Unlock()
+				return
+			}
+			delay = time.Duration((1 - r.tokens) / r.rate * float64(time.Second))
+		}
+
+		r.lock.Unlock()
+		r.sleep(delay)
+	}
+}
+
+// Observe reads the rate limiting headers of a response, pausing future calls
+// if the server says we are out of (or about to run out of) budget
+func (r *RateLimiter) Observe(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+
+	h := resp.Header
+
+	for _, header := range []string{"Retry-After", "Beta-Retry-After"} {
+		if until, ok := parseRetryAfter(h.Get(header)); ok {
+			slog.Warn(r.name + " - " + header + " received, pausing until " + until.Format(time.RFC3339))
+			r.pauseUntil(until)
+		}
+	}
+
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-", "X-Beta-RateLimit-", "Beta-RateLimit-"} {
+		remaining := h.Get(prefix + "Remaining")
+		if remaining == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(remaining, 64)
+		if err != nil || n > 0 {
+			continue
+		}
+		until, ok := parseRateLimitReset(h.Get(prefix + "Reset"))
+		if !ok {
+			until = time.Now().Add(r.backoffBase)
+		}
+		slog.Warn(r.name + " - " + prefix + "Remaining is 0, pausing until " + until.Format(time.RFC3339))
+		r.pauseUntil(until)
+	}
+
+	if strings.EqualFold(h.Get("X-RateLimit-NearLimit"), "true") {
+		// Drain the bucket so we drop to the steady-state rate
+		r.lock.Lock()
+		r.tokens = 0
+		r.lock.Unlock()
+	}
+}
+
+// Retry waits before a failed call is retried, returning false once retries are exhausted.
+// Header derived delays are used when present, otherwise exponential backoff with jitter.
+func (r *RateLimiter) Retry(resp *http.Response, attempt int) bool {
+	if attempt >= r.maxRetries {
+		return false
+	}
+
+	r.retryCount.Add(1)
+
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		r.lock.Lock()
+		paused := time.Now().Before(r.pausedUntil)
+		r.lock.Unlock()
+		if paused { // Observe has already told us how long to wait
+			return true
+		}
+	}
+
+	delay := r.backoff(attempt)
+	slog.Warn(r.name + " - retrying call in " + delay.String() + " (attempt " + strconv.Itoa(attempt+1) + " of " + strconv.Itoa(r.maxRetries) + ")")
+	r.pauseUntil(time.Now().Add(delay))
+
+	return true
+}
+
+// Throttled returns the total time spent waiting, and how many times we waited
+func (r *RateLimiter) Throttled() (time.Duration, int64, int64) {
+	return time.Duration(r.throttledNanos.Load()), r.throttleCount.Load(), r.retryCount.Load()
+}
+
+func (r *RateLimiter) backoff(attempt int) time.Duration {
+	d := r.backoffBase * time.Duration(1<<min(attempt, 30))
+	if d <= 0 || d > r.backoffMax {
+		d = r.backoffMax
+	}
+	// Equal jitter, so we never retry immediately but still spread out parallel callers
+	return d/2 + time.Duration(rand.Int64N(int64(d/2)+1))
+}
+
+func (r *RateLimiter) pauseUntil(t time.Time) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if t.After(r.pausedUntil) {
+		r.pausedUntil = t
+	}
+}
+
+func (r *RateLimiter) sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	r.throttledNanos.Add(int64(d))
+	r.throttleCount.Add(1)
+	time.Sleep(d)
+}
+
+func parseRetryAfter(val string) (time.Time, bool) {
+	val = strings.TrimSpace(val)
+	if val == "" {
+		return time.Time{}, false
+	}
+	if seconds, err := strconv.ParseFloat(val, 64); err == nil {
+		return time.Now().Add(time.Duration(seconds * float64(time.Second))), true
+	}
+	if t, err := http.ParseTime(val); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+func parseRateLimitReset(val string) (time.Time, bool) {
+	val = strings.TrimSpace(val)
+	if val == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05.999Z0700",
+		"2006-01-02T15:04Z",
+		"2006-01-02T15:04Z07:00",
+		time.RFC1123,
+		time.RFC1123Z,
+	} {
+		if t, err := time.Parse(layout, val); err == nil {
+			return t.Add(time.Second), true // One second buffer just in case
+		}
+	}
+	if n, err := strconv.ParseInt(val, 10, 64); err == nil {
+		if n > 1_000_000_000 { // Unix timestamp
+			return time.Unix(n, 0).Add(time.Second), true
+		}
+		return time.Now().Add(time.Duration(n) * time.Second), true // Delta seconds
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/MagicalTux/natsort"
+	jira "github.com/andygrunwald/go-jira/v2/cloud"
+	"github.com/pkg/errors"
+)
+
+var (
+	processedIssues     = map[string]bool{} // IssueIDs whose pages were written this run
+	dirtyIssues         = map[string]bool{} // IssueIDs to write even if cached, as a descendant changed
+	processedIssuesLock = &sync.Mutex{}
+)
+
+// IssueChild is a known child issue, with its own children
+type IssueChild struct {
+	Key      string // Page key
+	Title    string // "KEY | summary", resolves to the child's page
+	Marker   string // Status after `status` matching, e.g. TODO or DONE
+	DueDate  *time.Time
+	Children []IssueChild
+}
+
+// IssueRollup sums up every descendant of an issue, however deep
+type IssueRollup struct {
+	Total     int
+	Done      int // Closed, cancelled included as it's no longer outstanding
+	Overdue   int // Not done and due before today
+	Percent   int // Done out of total, rounded down
+	LatestDue *time.Time
+}
+
+var childIndex map[string][]*jira.Issue // Known children by parent IssueID, nil when knownIssues has changed since
+
+// childrenIndex returns the known children of every issue by parent IssueID, sorted by key.
+// It's built once for as long as knownIssues stays the same, rather than for every issue written.
+func childrenIndex() map[string][]*jira.Issue {
+
+	knownIssuesLock.RLock()
+	index := childIndex
+	knownIssuesLock.RUnlock()
+	if index != nil {
+		return index
+	}
+
+	knownIssuesLock.Lock()
+	defer knownIssuesLock.Unlock()
+
+	if childIndex != nil { // Built while waiting for the lock
+		return childIndex
+	}
+
+	childIndex = map[string][]*jira.Issue{}
+	for id, i := range knownIssues {
+		if i.Fields == nil || i.Fields.Parent == nil {
+			continue
+		}
+		baseURL, _ := SplitIssueID(id)
+		parent := baseURL + "browse/" + i.Fields.Parent.Key
+		childIndex[parent] = append(childIndex[parent], i)
+	}
+	for _, children := range childIndex {
+		slices.SortFunc(children, func(a, b *jira.Issue) int {
+			if natsort.Compare(a.Key, b.Key) {
+				return -1
+			}
+			return 1
+		})
+	}
+
+	return childIndex
+}
+
+// ChildRollup returns the tree of known descendants of an issue and their rollup, nil if it has no children
+func ChildRollup(project *JiraProject, issue *jira.Issue) ([]IssueChild, *IssueRollup, error) {
+
+	c := project.config
+
+	index := childrenIndex()
+
+	if len(index[IssueID(c, issue.Key)]) == 0 {
+		return nil, nil, nil
+	}
+
+	today := time.Date(startTime.Year(), startTime.Month(), startTime.Day(), 0, 0, 0, 0, time.UTC)
+	rollup := &IssueRollup{}
+	visited := map[string]bool{IssueID(c, issue.Key): true} // Guards against parent loops
+
+	var walk func(id string) ([]IssueChild, error)
+	walk = func(id string) (tree []IssueChild, err error) {
+
+		for _, child := range index[id] {
+			childID := IssueID(c, child.Key)
+			if visited[childID] {
+				continue
+			}
+			visited[childID] = true
+
+			childProject := ProjectOf(childID, child)
+			if childProject == nil || childProject.Options.Status.Default == nil {
+				childProject = project // Unconfigured or not yet processed, so go by the parent's options
+			}
+
+			item := IssueChild{
+				Key:    PageKey(c, child.Key),
+				Title:  LogseqTitle(c, child),
+				Marker: SimplifyStatus(childProject, child),
+			}
+
+			item.DueDate, err = GetDueDate(child, childProject)
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed in GetDueDate on "+child.Key)
+			}
+
+			rollup.Total += 1
+			if ClosedMarker(item.Marker) {
+				rollup.Done += 1
+			} else if item.DueDate != nil && item.DueDate.Before(today) {
+				rollup.Overdue += 1
+			}
+			if item.DueDate != nil && (rollup.LatestDue == nil || item.DueDate.After(*rollup.LatestDue)) {
+				rollup.LatestDue = item.DueDate
+			}
+
+			item.Children, err = walk(childID)
+			if err != nil {
+				return nil, err
+			}
+
+			tree = append(tree, item)
+		}
+
+		return tree, nil
+	}
+
+	tree, err := walk(IssueID(c, issue.Key))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if rollup.Total > 0 {
+		rollup.Percent = rollup.Done * 100 / rollup.Total
+	}
+
+	return tree, rollup, nil
+}
+
+// MarkProcessed records that an issue's page was written this run
+func MarkProcessed(c *JiraConfig, key string) {
+	processedIssuesLock.Lock()
+	defer processedIssuesLock.Unlock()
+	processedIssues[IssueID(c, key)] = true
+}
+
+// IsDirty returns whether an issue's page has to be written even if the issue itself is cached
+func IsDirty(c *JiraConfig, key string) bool {
+	processedIssuesLock.Lock()
+	defer processedIssuesLock.Unlock()
+	return dirtyIssues[IssueID(c, key)]
+}
+
+// RefreshAncestors writes the pages of every ancestor of an issue written this run,
+// so their children and rollups reflect the final state of knownIssues
+func RefreshAncestors() error {
+
+	processedIssuesLock.Lock()
+	processed := make([]string, 0, len(processedIssues))
+	for id := range processedIssues {
+		processed = append(processed, id)
+	}
+	processedIssuesLock.Unlock()
+
+	ancestors := []string{}
+	seen := map[string]bool{}
+
+	knownIssuesLock.RLock()
+	for _, id := range processed {
+		baseURL, _ := SplitIssueID(id)
+		for issue, ok := knownIssues[id]; ok && issue.Fields != nil && issue.Fields.Parent != nil; issue, ok = knownIssues[id] {
+			id = baseURL + "browse/" + issue.Fields.Parent.Key
+			if seen[id] {
+				break
+			}
+			seen[id] = true
+			ancestors = append(ancestors, id)
+		}
+	}
+	knownIssuesLock.RUnlock()
+
+	natsort.Sort(ancestors)
+
+	processedIssuesLock.Lock()
+	for _, id := range ancestors {
+		dirtyIssues[id] = true
+	}
+	processedIssuesLock.Unlock()
+
+	for _, id := range ancestors {
+		issue, ok := KnownIssue(id)
+		if !ok {
+			continue
+		}
+
+		project := ProjectOf(id, issue)
+		if project == nil || project.config.client == nil || !*project.Options.Outputs.Logseq.Enabled || PageCollision(project) != nil {
+			continue
+		}
+
+		slog.Info("Refreshing rollup of " + issue.Key)
+
+		err := ProcessIssue(nil, issue, project)
+		if err != nil {
+			return errors.Wrap(err, "Failed to refresh "+issue.Key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira/v2/cloud"
+)
+
+// testKnownIssues replaces the known issues for a test, restoring them after
+func testKnownIssues(t *testing.T, issues ...*jira.Issue) {
+	t.Helper()
+	previous := knownIssues
+	t.Cleanup(func() {
+		knownIssues = previous
+		childIndex = nil
+	})
+	knownIssues = map[string]*jira.Issue{}
+	for _, i := range issues {
+		knownIssues[i.Self] = i // Standing in for the IssueID
+	}
+	childIndex = nil
+}
+
+func testIssue(baseURL string, key string, parent string) *jira.Issue {
+	i := &jira.Issue{Key: key, Self: baseURL + "browse/" + key, Fields: &jira.IssueFields{}}
+	if parent != "" {
+		i.Fields.Parent = &jira.Parent{Key: parent}
+	}
+	return i
+}
+
+func TestKnownChildren(t *testing.T) {
+
+	acme := testInstance("https://acme.atlassian.net/")
+	other := testInstance("https://other.atlassian.net/")
+	testInstances(t, acme, other)
+
+	testKnownIssues(t,
+		testIssue("https://acme.atlassian.net/", "PROJ-1", ""),
+		testIssue("https://acme.atlassian.net/", "PROJ-10", "PROJ-1"),
+		testIssue("https://acme.atlassian.net/", "PROJ-9", "PROJ-1"),
+		testIssue("https://other.atlassian.net/", "PROJ-2", "PROJ-1"), // Same key, another instance
+	)
+
+	if children := KnownChildren(acme, "PROJ-1"); !reflect.DeepEqual(children, []string{"PROJ-9", "PROJ-10"}) {
+		t.Errorf("got %v, expected the acme children in order", children)
+	}
+	if children := KnownChildren(other, "PROJ-1"); !reflect.DeepEqual(children, []string{"PROJ-2"}) {
+		t.Errorf("got %v, expected only the other instance's child", children)
+	}
+
+	index := childrenIndex()
+	if reflect.ValueOf(childrenIndex()).Pointer() != reflect.ValueOf(index).Pointer() {
+		t.Error("index was built again without knownIssues changing")
+	}
+}
+
+// testRollupIssue is an issue of PROJ with a status and, unless zero, a due date
+func testRollupIssue(key string, parent string, status string, due time.Time) *jira.Issue {
+	i := testIssue("https://acme.atlassian.net/", key, parent)
+	i.Fields.Project = jira.Project{Key: "PROJ"}
+	i.Fields.Status = &jira.Status{Name: status}
+	i.Fields.Summary = "Issue " + key
+	i.Fields.Duedate = jira.Date(due)
+	return i
+}
+
+const testRollupConfig = `{"jira": {
+	"instances": [{
+		"connection": {"base_url": "https://acme.atlassian.net/", "display_name": "Me Myself"},
+		"projects": [{"key": "PROJ", "options": {"status": {"match": [
+			{"from": ["Done"], "to": "DONE"},
+			{"from": ["Won't Do"], "to": "CANCELED"}
+		]}}}]
+	}]
+}}`
+
+func TestChildRollup(t *testing.T) {
+
+	project := testJiraProject(t, testRollupConfig)
+
+	past := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := time.Date(2000, 2, 1, 0, 0, 0, 0, time.UTC)
+	future := time.Date(2999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, i := range []*jira.Issue{
+		testRollupIssue("PROJ-1", "", "In Progress", time.Time{}),
+		testRollupIssue("PROJ-2", "PROJ-1", "Done", past),         // Done, so not overdue however late
+		testRollupIssue("PROJ-4", "PROJ-2", "To Do", later),       // Overdue, two levels down
+		testRollupIssue("PROJ-5", "PROJ-2", "Won't Do", past),     // Cancelled counts as done
+		testRollupIssue("PROJ-3", "PROJ-1", "To Do", future),      // The latest due
+		testRollupIssue("PROJ-6", "PROJ-3", "To Do", time.Time{}), // No due date
+	} {
+		knownIssues[i.Self] = i
+	}
+
+	tree, rollup, err := ChildRollup(project, knownIssues["https://acme.atlassian.net/browse/PROJ-1"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &IssueRollup{Total: 5, Done: 2, Overdue: 1, Percent: 40, LatestDue: &future}
+	if !reflect.DeepEqual(rollup, expected) {
+		t.Errorf("got %+v, expected %+v", rollup, expected)
+	}
+
+	type node struct {
+		Key, Marker string
+		Children    []node
+	}
+	var nodes func(tree []IssueChild) []node
+	nodes = func(tree []IssueChild) (output []node) {
+		for _, child := range tree {
+			output = append(output, node{child.Key, child.Marker, nodes(child.Children)})
+		}
+		return
+	}
+	expectedTree := []node{
+		{"PROJ-2", "DONE", []node{{"PROJ-4", "TODO", nil}, {"PROJ-5", "CANCELED", nil}}},
+		{"PROJ-3", "TODO", []node{{"PROJ-6", "TODO", nil}}},
+	}
+	if got := nodes(tree); !reflect.DeepEqual(got, expectedTree) {
+		t.Errorf("got tree %+v, expected %+v", got, expectedTree)
+	}
+
+	tree, rollup, err = ChildRollup(project, knownIssues["https://acme.atlassian.net/browse/PROJ-6"])
+	if err != nil || tree != nil || rollup != nil {
+		t.Errorf("an issue without children got %+v, %+v, err %v", tree, rollup, err)
+	}
+}
+
+func TestChildRollupParentLoop(t *testing.T) {
+
+	project := testJiraProject(t, testRollupConfig)
+
+	for _, i := range []*jira.Issue{
+		testRollupIssue("PROJ-7", "PROJ-8", "To Do", time.Time{}),
+		testRollupIssue("PROJ-8", "PROJ-7", "Done", time.Time{}),
+	} {
+		knownIssues[i.Self] = i
+	}
+
+	_, rollup, err := ChildRollup(project, knownIssues["https://acme.atlassian.net/browse/PROJ-7"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rollup.Total != 1 || rollup.Done != 1 || rollup.Percent != 100 {
+		t.Errorf("got %+v, expected the loop to be followed once", rollup)
+	}
+}
+
+func TestRefreshAncestors(t *testing.T) {
+
+	project := testJiraProject(t, testRollupConfig)
+	c := project.config
+
+	previousProcessed, previousDirty := processedIssues, dirtyIssues
+	t.Cleanup(func() { processedIssues, dirtyIssues = previousProcessed, previousDirty })
+	processedIssues, dirtyIssues = map[string]bool{}, map[string]bool{}
+	yes := true
+	skipCached = &yes // Cached ancestors are written all the same
+
+	var err error
+	c.client, err = jira.NewClient(*c.Connection.BaseURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, i := range []*jira.Issue{
+		testRollupIssue("PROJ-1", "", "In Progress", time.Time{}),
+		testRollupIssue("PROJ-2", "PROJ-1", "In Progress", time.Time{}),
+		testRollupIssue("PROJ-3", "PROJ-2", "Done", time.Time{}),
+		testRollupIssue("PROJ-4", "", "To Do", time.Time{}), // Unrelated
+	} {
+		testCacheIssue(t, project, i, nil)
+	}
+
+	MarkProcessed(c, "PROJ-3")
+	if err := RefreshAncestors(); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, expected := range map[string]string{"PROJ-1": "children-done:: 1", "PROJ-2": "progress-percent:: 100"} {
+		if page := string(testIssuePage(t, project, key)); !strings.Contains(page, expected) {
+			t.Errorf("%s wasn't refreshed with %q:\n%s", key, expected, page)
+		}
+	}
+	for _, key := range []string{"PROJ-3", "PROJ-4"} {
+		if _, err := os.Stat(PageFilePath("jira", IssuePageName(project, key))); !os.IsNotExist(err) {
+			t.Errorf("%s was written, it isn't an ancestor", key)
+		}
+	}
+}
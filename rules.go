@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira/v2/cloud"
+	"github.com/pkg/errors"
+)
+
+// IssueRule matches issues by their fields, as found in search results so no further API calls are needed.
+// A rule either combines others with all, any or not, or tests a single field.
+type IssueRule struct {
+	All []*IssueRule `json:"all"` // Matches if every one of these matches
+	Any []*IssueRule `json:"any"` // Matches if at least one of these matches
+	Not *IssueRule   `json:"not"` // Matches if this doesn't
+
+	Field   *string   `json:"field"`   // type, label, component, assignee, reporter, priority, status, resolution, age, updated, or a custom field ID
+	Is      []*string `json:"is"`      // Matches if any value of the field is one of these
+	Matches *string   `json:"matches"` // Matches if any value of the field matches this regular expression
+	Min     *float64  `json:"min"`     // Matches if the field is a number, or days for age and updated, of at least this
+	Max     *float64  `json:"max"`     // Matches if the field is a number, or days for age and updated, of at most this
+	Set     *bool     `json:"set"`     // Matches if the field has, or with false hasn't, a value
+}
+
+var (
+	ruleMatchers     = map[string]*regexp.Regexp{}
+	ruleMatchersLock = &sync.Mutex{}
+)
+
+func (r *IssueRule) UnmarshalJSON(data []byte) error {
+	type fields IssueRule // Without this method, so it doesn't recurse
+	err := json.Unmarshal(data, (*fields)(r))
+	if err != nil {
+		return err
+	}
+
+	if r.Matches != nil { // Compiled as the config loads, so a bad pattern stops the run rather than failing every issue
+		_, err = ruleMatcher(*r.Matches)
+	}
+	return err
+}
+
+// IncludeIssue returns whether an issue passes the filter and type options of its project
+func IncludeIssue(project *JiraProject, issue *jira.Issue) (bool, error) {
+
+	for _, matcher := range project.Options.Type {
+		if matcher.Exclude != nil && *matcher.Exclude && MatchAny(matcher.From, TypeTarget(issue)) {
+			return false, nil
+		}
+	}
+
+	filter := project.Options.Filter
+
+	if filter.Include != nil {
+		ok, err := filter.Include.Match(issue)
+		if err != nil || !ok {
+			return false, errors.Wrap(err, "Failed in include rule")
+		}
+	}
+
+	if filter.Exclude != nil {
+		ok, err := filter.Exclude.Match(issue)
+		if err != nil || ok {
+			return false, errors.Wrap(err, "Failed in exclude rule")
+		}
+	}
+
+	return true, nil
+}
+
+// Match returns whether an issue matches the rule
+func (r *IssueRule) Match(issue *jira.Issue) (bool, error) {
+
+	switch {
+	case r.All != nil:
+		for _, rule := range r.All {
+			ok, err := rule.Match(issue)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+
+	case r.Any != nil:
+		for _, rule := range r.Any {
+			ok, err := rule.Match(issue)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+
+	case r.Not != nil:
+		ok, err := r.Not.Match(issue)
+		return !ok, err
+
+	case r.Field == nil:
+		return false, errors.New("Rule has none of all, any, not or field")
+	}
+
+	values, number := ruleValues(*r.Field, issue)
+
+	if r.Set != nil && *r.Set != (len(values) > 0) {
+		return false, nil
+	}
+
+	if r.Min != nil || r.Max != nil {
+		if number == nil {
+			return false, nil
+		}
+		if (r.Min != nil && *number < *r.Min) || (r.Max != nil && *number > *r.Max) {
+			return false, nil
+		}
+	}
+
+	if r.Is != nil {
+		found := false
+		for _, want := range r.Is {
+			if slices.Contains(values, *want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+
+	if r.Matches != nil {
+		matcher, err := ruleMatcher(*r.Matches)
+		if err != nil {
+			return false, err
+		}
+		if !slices.ContainsFunc(values, matcher.MatchString) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func ruleMatcher(pattern string) (*regexp.Regexp, error) {
+	ruleMatchersLock.Lock()
+	defer ruleMatchersLock.Unlock()
+
+	if m, ok := ruleMatchers[pattern]; ok {
+		return m, nil
+	}
+
+	m, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.Wrap(err, "Bad rule pattern `"+pattern+"`")
+	}
+	ruleMatchers[pattern] = m
+	return m, nil
+}
+
+// ruleValues returns the values of a field to test, and its number if it has one
+func ruleValues(field string, issue *jira.Issue) (values []string, number *float64) {
+
+	f := issue.Fields
+
+	days := func(t time.Time) *float64 {
+		d := startTime.Sub(t).Hours() / 24
+		return &d
+	}
+
+	switch field {
+	case "type":
+		values = append(values, f.Type.Name)
+	case "label":
+		values = append(values, f.Labels...)
+	case "component":
+		for _, c := range f.Components {
+			values = append(values, c.Name)
+		}
+	case "assignee":
+		if f.Assignee != nil {
+			values = append(values, f.Assignee.DisplayName, f.Assignee.AccountID)
+		}
+	case "reporter":
+		if f.Reporter != nil {
+			values = append(values, f.Reporter.DisplayName, f.Reporter.AccountID)
+		}
+	case "priority":
+		if f.Priority != nil {
+			values = append(values, f.Priority.Name, f.Priority.ID)
+		}
+	case "status":
+		if f.Status != nil {
+			values = append(values, f.Status.Name)
+		}
+	case "resolution":
+		if f.Resolution != nil {
+			values = append(values, f.Resolution.Name)
+		}
+	case "age":
+		number = days(time.Time(f.Created))
+		values = append(values, strconv.FormatFloat(*number, 'f', -1, 64))
+	case "updated":
+		number = days(time.Time(f.Updated))
+		values = append(values, strconv.FormatFloat(*number, 'f', -1, 64))
+	default:
+		values, number = customFieldRuleValues(f.Unknowns[field])
+	}
+
+	return
+}
+
+// customFieldRuleValues flattens a raw custom field into the strings it holds,
+// using the value of options and the name of users and other objects
+func customFieldRuleValues(val any) (values []string, number *float64) {
+	switch v := val.(type) {
+	case nil:
+	case string:
+		if v != "" {
+			values = append(values, v)
+		}
+		if n, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			number = &n
+		}
+	case float64:
+		values = append(values, strconv.FormatFloat(v, 'f', -1, 64))
+		number = &v
+	case bool:
+		values = append(values, strconv.FormatBool(v))
+	case []any:
+		for _, item := range v {
+			itemValues, _ := customFieldRuleValues(item)
+			values = append(values, itemValues...)
+		}
+	case map[string]any:
+		for _, key := range []string{"value", "displayName", "name", "accountId"} {
+			if s, ok := v[key].(string); ok && s != "" {
+				values = append(values, s)
+			}
+		}
+		if child, ok := v["child"]; ok { // Cascading selects
+			childValues, _ := customFieldRuleValues(child)
+			values = append(values, childValues...)
+		}
+	}
+	return
+}
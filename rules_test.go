@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira/v2/cloud"
+)
+
+func TestRuleBadRegex(t *testing.T) {
+	for _, filter := range []string{
+		`{"exclude": {"field": "label", "matches": "(unclosed"}}`,
+		`{"include": {"all": [{"field": "status", "is": ["To Do"]}, {"not": {"field": "label", "matches": "[a-"}}]}}`,
+	} {
+		options := JiraOptions{}
+		err := json.Unmarshal([]byte(`{"filter": `+filter+`}`), &options)
+		if err == nil || !strings.Contains(err.Error(), "Bad rule pattern") {
+			t.Errorf("expected %s to fail to load, got %v", filter, err)
+		}
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+
+	project := &JiraProject{}
+	if err := json.Unmarshal([]byte(`{"filter": {"exclude": {"any": [
+		{"field": "label", "matches": "^wontfix"},
+		{"not": {"field": "status", "set": true}}
+	]}}}`), &project.Options); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		labels   []string
+		status   *jira.Status
+		included bool
+	}{
+		{[]string{"backend"}, &jira.Status{Name: "To Do"}, true},
+		{[]string{"backend", "wontfix-later"}, &jira.Status{Name: "To Do"}, false},
+		{nil, nil, false},
+	}
+
+	for _, test := range tests {
+		issue := &jira.Issue{Fields: &jira.IssueFields{Labels: test.labels, Status: test.status}}
+		included, err := IncludeIssue(project, issue)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if included != test.included {
+			t.Errorf("labels %v and status %v included %v, expected %v", test.labels, test.status, included, test.included)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// What Logseq reads meaning into in plain text, each neutralised with a backslash
+var logseqSyntax = []struct {
+	matcher *regexp.Regexp
+	repl    string
+}{
+	{ // Tags, #tag and #[[tag]], and priorities like [#A]
+		matcher: regexp.MustCompile(`(^|[^\p{L}\p{N}_\\&])#([^\s#])`),
+		repl:    `$1\#$2`,
+	},
+	{ // Page references
+		matcher: regexp.MustCompile(`\[\[`),
+		repl:    `\[\[`,
+	},
+	{ // Block references
+		matcher: regexp.MustCompile(`\(\(`),
+		repl:    `\(\(`,
+	},
+	{ // Macros, queries and embeds
+		matcher: regexp.MustCompile(`\{\{`),
+		repl:    `\{\{`,
+	},
+	{ // Properties, key:: value
+		matcher: regexp.MustCompile(`([A-Za-z0-9_\-?]+)::`),
+		repl:    `$1\::`,
+	},
+	{ // Scheduling, which puts a block in the agenda
+		matcher: regexp.MustCompile(`\b(SCHEDULED|DEADLINE):`),
+		repl:    `$1\:`,
+	},
+}
+
+// Task markers, which Logseq only takes at the start of a block, and the same markers neutralised as code
+var (
+	logseqMarkerMatcher        = regexp.MustCompile(`^(` + strings.Join(logseqMarkers, "|") + `)(\s|$)`)
+	logseqEscapedMarkerMatcher = regexp.MustCompile("^`(" + strings.Join(logseqMarkers, "|") + ")`" + `(\s|$)`)
+)
+
+// LogseqText neutralises the tags, references, macros, properties and scheduling in text from Jira,
+// so it reads as it did there. Links made on purpose are added around it, and left alone.
+func LogseqText(s string) string {
+	for _, syntax := range logseqSyntax {
+		s = syntax.matcher.ReplaceAllString(s, syntax.repl)
+	}
+	return s
+}
+
+// LogseqBlockStart neutralises a task marker starting a block by making it code, as letters can't be escaped
+func LogseqBlockStart(line string) string {
+	return logseqMarkerMatcher.ReplaceAllString(line, "`$1`$2")
+}
+
+// LogseqBlockUnstart undoes LogseqBlockStart, for text going back to Jira
+func LogseqBlockUnstart(line string) string {
+	return logseqEscapedMarkerMatcher.ReplaceAllString(line, "$1$2")
+}
+
+// LogseqValue neutralises text from Jira for a property value, which must also stay on one line
+func LogseqValue(s string) string {
+	return LogseqText(strings.Join(strings.Fields(s), " "))
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"archive/zip"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var sink Sink = &FSSink{} // Replaced according to -sink in main
+
+var cacheFiles = &FSSink{} // The cache isn't output, later runs need it whatever the sink
+
+// Sink is where every output goes, pages, assets and spreadsheets
+type Sink interface {
+	WriteFile(path string, contents []byte) error
+	ReadFile(path string) ([]byte, error) // Returns an error wrapping os.ErrNotExist for missing files
+	Exists(path string) (bool, error)
+	Remove(path string) error // Missing files aren't an error
+	Close() error             // Flushes anything held back, called once at the end of a run
+}
+
+// NewSink returns the sink named by the -sink flag
+func NewSink(kind string, sinkPath string) (Sink, error) {
+	switch kind {
+	case "", "fs":
+		return &FSSink{}, nil
+	case "memory":
+		return NewMemorySink(), nil
+	case "zip":
+		if sinkPath == "" {
+			return nil, errors.New("-sink-path is required for the zip sink")
+		}
+		return &ZipSink{MemorySink: NewMemorySink(), path: sinkPath}, nil
+	}
+	return nil, errors.New("Unknown sink " + kind + ", expected one of fs, zip or memory")
+}
+
+// FSSink writes straight to the filesystem
+type FSSink struct{}
+
+func (s *FSSink) WriteFile(filePath string, contents []byte) error {
+
+	dir := regexp.MustCompile("[^/]*$").ReplaceAllString(filePath, "")
+
+	if dir != "" {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return errors.Wrap(err, "Couldn't make directory "+dir)
+		}
+	}
+
+	return os.WriteFile(filePath, contents, 0644)
+}
+
+func (s *FSSink) ReadFile(filePath string) ([]byte, error) {
+	return os.ReadFile(filePath)
+}
+
+func (s *FSSink) Exists(filePath string) (bool, error) {
+	_, err := os.Stat(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *FSSink) Remove(filePath string) error {
+	err := os.Remove(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *FSSink) Close() error {
+	return nil
+}
+
+// MemorySink holds everything written in memory, for dry runs.
+// Reads fall through to the filesystem, so existing pages and assets are still seen.
+type MemorySink struct {
+	lock    sync.RWMutex
+	files   map[string][]byte
+	removed map[string]bool // Hides files on the filesystem
+	fs      FSSink
+}
+
+func NewMemorySink() *MemorySink {
+	return &MemorySink{files: map[string][]byte{}, removed: map[string]bool{}}
+}
+
+// memoryKey normalises a path, so "./a/b" and "a//b" are the same file
+func memoryKey(filePath string) string {
+	return path.Clean(filePath)
+}
+
+func (s *MemorySink) WriteFile(filePath string, contents []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.files[memoryKey(filePath)] = slices.Clone(contents)
+	delete(s.removed, memoryKey(filePath))
+	return nil
+}
+
+func (s *MemorySink) ReadFile(filePath string) ([]byte, error) {
+	s.lock.RLock()
+	contents, ok := s.files[memoryKey(filePath)]
+	removed := s.removed[memoryKey(filePath)]
+	s.lock.RUnlock()
+
+	if ok {
+		return slices.Clone(contents), nil
+	}
+	if removed {
+		return nil, &os.PathError{Op: "open", Path: filePath, Err: os.ErrNotExist}
+	}
+	return s.fs.ReadFile(filePath)
+}
+
+func (s *MemorySink) Exists(filePath string) (bool, error) {
+	s.lock.RLock()
+	_, ok := s.files[memoryKey(filePath)]
+	removed := s.removed[memoryKey(filePath)]
+	s.lock.RUnlock()
+
+	if ok {
+		return true, nil
+	}
+	if removed {
+		return false, nil
+	}
+	return s.fs.Exists(filePath)
+}
+
+func (s *MemorySink) Remove(filePath string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.files, memoryKey(filePath))
+	s.removed[memoryKey(filePath)] = true
+	return nil
+}
+
+// Files returns the paths written so far, sorted
+func (s *MemorySink) Files() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	paths := make([]string, 0, len(s.files))
+	for p := range s.files {
+		paths = append(paths, p)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+func (s *MemorySink) Close() error {
+	size := 0
+	for _, p := range s.Files() {
+		slog.Debug("Dry run, not writing " + p)
+		size += len(s.files[p])
+	}
+	slog.Info("Dry run, " + strconv.Itoa(len(s.files)) + " files totalling " + strconv.Itoa(size) + " bytes not written")
+	return nil
+}
+
+// ZipSink collects everything written into a zip archive, e.g. to share a graph snapshot.
+// Files are held in memory until Close, so a file written twice only appears once.
+type ZipSink struct {
+	*MemorySink
+	path string
+}
+
+func (s *ZipSink) Close() error {
+
+	f, err := os.Create(s.path)
+	if err != nil {
+		return errors.Wrap(err, "Couldn't create archive "+s.path)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	modified := time.Now()
+
+	for _, p := range s.Files() {
+		name := zipEntryName(p)
+
+		entry, err := w.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: modified,
+		})
+		if err != nil {
+			return errors.Wrap(err, "Couldn't add "+name+" to archive")
+		}
+
+		_, err = entry.Write(s.files[p])
+		if err != nil {
+			return errors.Wrap(err, "Couldn't write "+name+" to archive")
+		}
+	}
+
+	err = w.Close()
+	if err != nil {
+		return errors.Wrap(err, "Couldn't finish archive "+s.path)
+	}
+
+	slog.Info("Wrote " + strconv.Itoa(len(s.files)) + " files to " + s.path)
+
+	return nil
+}
+
+// zipEntryName makes a path relative to the working directory where possible, as archives can't hold absolute paths
+func zipEntryName(filePath string) string {
+	if path.IsAbs(filePath) {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, filePath); err == nil {
+				filePath = filepath.ToSlash(rel)
+			}
+		}
+	}
+	return regexp.MustCompile(`^(\.\./|/)+`).ReplaceAllString(filePath, "")
+}
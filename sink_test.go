@@ -0,0 +1,166 @@
+package main
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testSinkRoundTrip writes, reads, checks for and removes a file through a sink
+func testSinkRoundTrip(t *testing.T, s Sink, dir string) {
+	t.Helper()
+
+	filePath := filepath.Join(dir, "pages", "jira", "PROJ-1.md")
+
+	if err := s.WriteFile(filePath, []byte("- first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteFile(filePath, []byte("- second")); err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := s.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "- second" {
+		t.Errorf("read %q, expected the last write", contents)
+	}
+
+	if exists, err := s.Exists(filePath); err != nil || !exists {
+		t.Errorf("written file doesn't exist, err %v", err)
+	}
+
+	if err := s.Remove(filePath); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Remove(filePath); err != nil {
+		t.Errorf("removing a missing file failed: %v", err)
+	}
+	if exists, err := s.Exists(filePath); err != nil || exists {
+		t.Errorf("removed file still exists, err %v", err)
+	}
+	if _, err := s.ReadFile(filePath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("reading a removed file gave %v, expected os.ErrNotExist", err)
+	}
+}
+
+func TestFSSink(t *testing.T) {
+	dir := t.TempDir()
+	testSinkRoundTrip(t, &FSSink{}, dir)
+
+	s := &FSSink{}
+	if err := s.WriteFile(filepath.Join(dir, "assets", "a.png"), []byte("png")); err != nil {
+		t.Fatal(err)
+	}
+	if contents, err := os.ReadFile(filepath.Join(dir, "assets", "a.png")); err != nil || string(contents) != "png" {
+		t.Errorf("file wasn't written to disk, got %q, err %v", contents, err)
+	}
+}
+
+func TestMemorySink(t *testing.T) {
+	dir := t.TempDir()
+	s := NewMemorySink()
+	testSinkRoundTrip(t, s, dir)
+
+	// Nothing reaches the disk
+	if _, err := os.Stat(filepath.Join(dir, "pages")); !os.IsNotExist(err) {
+		t.Errorf("memory sink wrote to disk, %v", err)
+	}
+
+	// Existing files are read through, and removing them only hides them
+	existing := filepath.Join(dir, "existing.md")
+	if err := os.WriteFile(existing, []byte("on disk"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if contents, err := s.ReadFile(existing); err != nil || string(contents) != "on disk" {
+		t.Errorf("didn't read through to disk, got %q, err %v", contents, err)
+	}
+	if err := s.Remove(existing); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := s.Exists(existing); exists {
+		t.Error("removed file is still seen")
+	}
+	if _, err := os.Stat(existing); err != nil {
+		t.Errorf("file on disk was removed: %v", err)
+	}
+}
+
+func TestZipSink(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "graph.zip")
+
+	s, err := NewSink("zip", archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testSinkRoundTrip(t, s, "notes")
+
+	files := map[string]string{
+		"notes/pages/jira/PROJ-1.md":          "- page",
+		"../notes/assets/jira/jira_10001.png": "png",
+	}
+	for p, contents := range files {
+		if err := s.WriteFile(p, []byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	expected := map[string]string{
+		"notes/pages/jira/PROJ-1.md":       "- page",
+		"notes/assets/jira/jira_10001.png": "png",
+	}
+	if len(r.File) != len(expected) {
+		t.Errorf("archive holds %d files, expected %d", len(r.File), len(expected))
+	}
+	for _, f := range r.File {
+		want, ok := expected[f.Name]
+		if !ok {
+			t.Errorf("unexpected entry %s", f.Name)
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(contents) != want {
+			t.Errorf("%s holds %q, expected %q", f.Name, contents, want)
+		}
+	}
+}
+
+// The cache goes to disk whatever the sink, so the next run can use it
+func TestCacheBypassesSink(t *testing.T) {
+	previous := sink
+	t.Cleanup(func() { sink = previous })
+	sink = NewMemorySink()
+
+	cached := filepath.Join(t.TempDir(), "cache", "knownIssues.json")
+	if err := WriteCacheFile(cached, []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cached); err != nil {
+		t.Errorf("cache wasn't written to disk: %v", err)
+	}
+	if contents, err := ReadCacheFile(cached); err != nil || string(contents) != "{}" {
+		t.Errorf("read %q from the cache, err %v", contents, err)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -35,8 +36,8 @@ func (c Config) ProcessTables() error {
 
 				issues := []*jira.Issue{}
 
-				for _, issue := range knownIssues {
-					if issue.Fields.Project.Key == *project.Key {
+				for id, issue := range knownIssues {
+					if issue.Fields.Project.Key == *project.Key && InstanceOf(id) == instance {
 						issues = append(issues, issue)
 					}
 				}
@@ -44,7 +45,7 @@ func (c Config) ProcessTables() error {
 				topLevel := []*jira.Issue{}
 
 				for _, issue := range issues {
-					if p, ok := parents[issue.Key]; !ok || p == nil {
+					if p, ok := parents[IssueID(instance, issue.Key)]; !ok || p == nil {
 						topLevel = append(topLevel, issue)
 					}
 				}
@@ -56,6 +57,14 @@ func (c Config) ProcessTables() error {
 					"Delay",
 				}
 
+				computedColumns := map[string]bool{}
+				for _, computed := range project.Options.ComputedFields {
+					if computed.To != nil && computed.Table != nil && *computed.Table {
+						header = append(header, *computed.To)
+						computedColumns[*computed.To] = true
+					}
+				}
+
 				i := 1
 
 				linkStyle, err := f.NewStyle(&excelize.Style{
@@ -116,7 +125,7 @@ func (c Config) ProcessTables() error {
 
 				for _, issue := range topLevel {
 
-					for _, childIssue := range children[issue.Key] {
+					for _, childIssue := range children[IssueID(instance, issue.Key)] {
 
 						issueList = append(issueList, struct {
 							Issue  string
@@ -185,13 +194,28 @@ func (c Config) ProcessTables() error {
 						return err
 					}
 
+					computed := map[string]string{}
+					if len(computedColumns) > 0 {
+						translated, err := TranslateCustomFields(project, knownIssues[childIssue])
+						if err != nil {
+							return err
+						}
+						properties, err := ComputeFields(project, knownIssues[childIssue], dateEndTime, translated)
+						if err != nil {
+							return err
+						}
+						for _, p := range properties {
+							computed[p.Key] = p.Value
+						}
+					}
+
 					dateEndBaseline := ""
 
 					for _, customField := range project.Options.CustomFields {
 						switch *customField.To {
 						case "date-due-baseline":
-							val, ok := customFields[*customField.From]
-							if val != "" && val != "<nil>" && ok {
+							val, ok := customFields.String(*customField.From)
+							if ok {
 								dateEndBaselineTime, err := time.Parse("2006-01-02", val)
 								if err != nil {
 									return err
@@ -256,6 +280,14 @@ func (c Config) ProcessTables() error {
 								val = int(end.Sub(endBaseline).Hours() / 24)
 
 							}
+						default:
+							if computedColumns[header[j]] {
+								if f, err := strconv.ParseFloat(computed[header[j]], 64); err == nil {
+									val = f
+								} else {
+									val = computed[header[j]]
+								}
+							}
 						}
 
 						switch t {
@@ -281,7 +313,12 @@ func (c Config) ProcessTables() error {
 				}
 
 				// Save spreadsheet by the given path.
-				err = f.SaveAs("./table_" + *project.Key + ".xlsx")
+				buffer, err := f.WriteToBuffer()
+				if err != nil {
+					return err
+				}
+
+				err = WriteFile("./table_"+*project.Key+".xlsx", buffer.Bytes())
 				if err != nil {
 					return err
 				}
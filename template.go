@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira/v2/cloud"
+	"github.com/pkg/errors"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+//go:embed templates/issue.md.tmpl
+var defaultIssueTemplate string
+
+var (
+	issueTemplates     = map[string]*template.Template{} // Parsed templates by name and path, an empty path is the built-in default
+	issueTemplatesLock = &sync.Mutex{}
+)
+
+// IssuePage is the data model handed to issue page templates.
+// Text fields are already transformed for Logseq, e.g. names are [[linked]] if link_names is set.
+type IssuePage struct {
+	Issue     *jira.Issue // Issue as returned by the search
+	FullIssue *jira.Issue // Issue as returned by a full fetch, includes custom fields and comments
+
+	Key          string
+	Title        string // Page title, "KEY | summary", or "namespace/KEY" when namespaced
+	Type         string // Issue type after `type` substitution
+	Project      string
+	URL          string
+	Summary      string
+	Status       string // Jira status name
+	StatusSimple string // Status after `status` matching, e.g. TODO or DONE
+	Priority     string // Logseq priority after `priority` matching, A, B or C, empty for none
+	JiraPriority string // Jira priority name
+	Parent       string // Parent key, empty if there is none
+	Assignee     string
+	Reporter     string
+	Watchers     []string
+	Created      time.Time
+
+	DueDate         *time.Time // Due date, possibly inherited from a parent
+	DueDateExplicit bool       // Whether the due date was set on this issue itself
+	DueDateSource   string     // Key of the issue the due date was taken from
+
+	HasClosedParent bool
+
+	CustomFields   []Property       // Translated custom fields
+	ComputedFields []Property       // Results of computed_fields
+	Description    []string         // Description as Logseq blocks
+	Links          []IssueLinkGroup // Outward links, sorted by link type
+	Children       []string         // Keys of known child issues
+	ChildTree      []IssueChild     // Known descendants, empty if there are none
+	Rollup         *IssueRollup     // Progress across every descendant, nil if there are none
+	Task           *IssueTask       // Nil unless a task block should be included
+	Comments       []IssueComment   // Empty unless include_comments is set
+	Properties     []Property       // Page properties, in order
+	Options        *JiraOptions     // Options in effect for this issue
+}
+
+type IssueLinkGroup struct {
+	Type   string   // Outward description of the link type, e.g. "blocks"
+	Issues []string // Linked issue keys
+}
+
+type IssueTask struct {
+	Marker     string     // Logseq task marker, e.g. TODO
+	Priority   string     // Logseq priority, A, B or C, empty for none
+	Key        string     // Issue key
+	ID         string     // Stable block UUID
+	Due        *time.Time // The issue's own due date, if any
+	Properties []Property // Block properties, in order
+}
+
+type IssueComment struct {
+	Author  string
+	Created time.Time
+	Updated time.Time
+	Lines   []string // Comment body as Logseq blocks
+}
+
+func (p *IssuePage) AddProperty(key, value string) {
+	p.Properties = append(p.Properties, Property{key, value})
+}
+
+var templateFuncs = template.FuncMap{
+	"date": func(t any) string { // Jan 2nd, 2006
+		return DateFormat(asTime(t))
+	},
+	"sortable": func(t any) string { // 20060102
+		return asTime(t).Format("20060102")
+	},
+	"agenda": func(t any) string { // 2006-01-02 Mon
+		return asTime(t).Format("2006-01-02 Mon")
+	},
+	"title": func(s string) string {
+		return cases.Title(language.AmericanEnglish).String(s)
+	},
+	"indent": func(prefix string, lines []string) []string {
+		return PrefixStringSlice(lines, prefix)
+	},
+	"join": func(sep string, s []string) string {
+		return strings.Join(s, sep)
+	},
+	"dict": func(pairs ...any) map[string]any { // dict "Key" value "Other" value
+		m := map[string]any{}
+		for i := 0; i+1 < len(pairs); i += 2 {
+			m[pairs[i].(string)] = pairs[i+1]
+		}
+		return m
+	},
+}
+
+func asTime(t any) time.Time {
+	switch t := t.(type) {
+	case time.Time:
+		return t
+	case *time.Time:
+		if t != nil {
+			return *t
+		}
+	}
+	return time.Time{}
+}
+
+// IssueTemplate returns the parsed page template for a project, falling back to the built-in default
+func IssueTemplate(project *JiraProject) (*template.Template, error) {
+	return LoadTemplate(project.Options.Template, "issue", defaultIssueTemplate)
+}
+
+// LoadTemplate returns the parsed template at a path, or the given built-in default if the path is unset
+func LoadTemplate(path *string, name string, defaultText string) (*template.Template, error) {
+
+	templatePath := ""
+	if path != nil {
+		templatePath = *path
+	}
+
+	issueTemplatesLock.Lock()
+	defer issueTemplatesLock.Unlock()
+
+	cacheKey := name + ":" + templatePath
+
+	if t, ok := issueTemplates[cacheKey]; ok {
+		return t, nil
+	}
+
+	text := defaultText
+
+	if templatePath != "" {
+		raw, err := os.ReadFile(templatePath)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to read template "+templatePath)
+		}
+		text = string(raw)
+	}
+
+	t, err := template.New(templatePath).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse template "+templatePath)
+	}
+
+	issueTemplates[cacheKey] = t
+
+	return t, nil
+}
+
+// RenderIssuePage renders an issue page with the project's template.
+// A single trailing newline is trimmed from the result.
+func RenderIssuePage(project *JiraProject, page *IssuePage) ([]byte, error) {
+
+	t, err := IssueTemplate(project)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+
+	err = t.Execute(buf, page)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to execute template for "+page.Key)
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// KnownChildren returns the page keys of known issues on the instance whose parent is the given key
+func KnownChildren(c *JiraConfig, key string) (children []string) {
+	for _, child := range childrenIndex()[IssueID(c, key)] {
+		children = append(children, PageKey(c, child.Key))
+	}
+	return
+}
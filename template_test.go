@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira/v2/cloud"
+	"github.com/vbauerster/mpb/v8"
+)
+
+// testJiraProject configures the first project of configJSON over the default options,
+// with its cache and graph in a temporary directory, restoring the config and known issues after
+func testJiraProject(t *testing.T, configJSON string) *JiraProject {
+	t.Helper()
+
+	previousConfig, previousIssues, previousMatchers := config, knownIssues, issueUrlMatchers
+	t.Cleanup(func() {
+		config, knownIssues, issueUrlMatchers = previousConfig, previousIssues, previousMatchers
+		childIndex = nil
+	})
+
+	no, yes := false, true
+	debug, recent, ignoreCache, ignoreAttachmentBlacklist, skipCached, includeStackTrace = &no, &no, &no, &no, &no, &yes
+	progress = mpb.New(mpb.WithOutput(io.Discard))
+	jiraApiCalls, jiraCacheHits = progress.AddBar(0), progress.AddBar(0)
+
+	raw, err := os.ReadFile("default_options.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(raw, &defaultOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	config = Config{}
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		t.Fatal(err)
+	}
+	cacheRoot, logseqRoot := filepath.Join(root, "cache"), filepath.Join(root, "notes")
+	config.Jira.Options.Paths.CacheRoot = &cacheRoot
+	config.Jira.Options.Outputs.Logseq.LogseqRoot = &logseqRoot
+
+	options, err := UnderlayOptions(&defaultOptions.Jira, &config.Jira.Options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Jira.Options = *options
+
+	c := config.Jira.Instances[0]
+	options, err = UnderlayOptions(&config.Jira.Options, &c.Options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Options = *options
+	c.progress = map[string]*mpb.Bar{}
+
+	project := c.Projects[0]
+	project.config = c
+	c.progress[*project.Key] = progress.AddBar(0)
+	options, err = UnderlayOptions(&c.Options, &project.Options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	project.Options = *options
+
+	issueUrlMatchers = nil
+	knownIssues = map[string]*jira.Issue{}
+	childIndex = nil
+
+	return project
+}
+
+// testCacheIssue makes an issue known and caches it in full with its watchers, so it's processed without fetching
+func testCacheIssue(t *testing.T, project *JiraProject, issue *jira.Issue, watchers []string) {
+	t.Helper()
+
+	knownIssues[IssueID(project.config, issue.Key)] = issue
+	childIndex = nil
+
+	cachedPath, dir, err := GetCachedIssuePath(project, issue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for p, v := range map[string]any{cachedPath: issue, cachedPath[:len(cachedPath)-len(".json")] + "_watchers.json": watchers} {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, raw, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// testIssuePage reads the page written for an issue
+func testIssuePage(t *testing.T, project *JiraProject, key string) []byte {
+	t.Helper()
+	output, err := os.ReadFile(PageFilePath("jira", IssuePageName(project, key)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return output
+}
+
+// The built-in template must write pages exactly as they were written before templates,
+// testdata/template/issue.md being the page written then for the issue in issue.json
+func TestDefaultIssueTemplate(t *testing.T) {
+
+	raw, err := os.ReadFile("testdata/template/issue.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	issue := &jira.Issue{}
+	if err := json.Unmarshal(raw, issue); err != nil {
+		t.Fatal(err)
+	}
+
+	project := testJiraProject(t, `{"jira": {
+		"users": [{"account_id": "abc123", "display_name": "John Doe"}],
+		"options": {
+			"outputs": {"logseq": {"include_task": true, "link_dates": true}},
+			"custom_fields": [{"from": "customfield_10015", "to": "date-start", "as": "date_sortable"}]
+		},
+		"instances": [{
+			"connection": {"base_url": "https://example.atlassian.net/", "display_name": "Me Myself"},
+			"projects": [{"key": "PRJ"}]
+		}]
+	}}`)
+	testCacheIssue(t, project, issue, []string{"Alice A", "Bob B"})
+
+	if err := ProcessIssue(nil, issue, project); err != nil {
+		t.Fatal(err)
+	}
+	output := testIssuePage(t, project, issue.Key)
+
+	// Not a golden file -update may rewrite, as it records the output before templates
+	expected, err := os.ReadFile("testdata/template/issue.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != string(expected) {
+		t.Errorf("page differs from testdata/template/issue.md\n--- got ---\n%s\n--- expected ---\n%s", output, expected)
+	}
+}
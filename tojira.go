@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// ToJira converts a Logseq page, or part of one, to Jira wiki markup or ADF and prints it.
+// It reads the file given, or standard input if there is none.
+func ToJira(format string, path string) error {
+
+	var input []byte
+	var err error
+	if path == "" || path == "-" {
+		input, err = io.ReadAll(bufio.NewReader(os.Stdin))
+	} else {
+		input, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return errors.Wrap(err, "Failed to read Markdown to convert")
+	}
+
+	resolver, err := JiraRefs()
+	if err != nil {
+		return err
+	}
+
+	doc := ParseLogseq(string(input), resolver)
+
+	switch format {
+	case "", "wiki":
+		fmt.Println(RenderWiki(doc))
+	case "adf":
+		output, err := RenderADF(doc)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(output))
+	default:
+		return errors.New("Unknown format `" + format + "`, expected wiki or adf")
+	}
+
+	return nil
+}
+
+// JiraRefs resolves references in Logseq Markdown against the configured instances and users, and the graph
+func JiraRefs() (LogseqResolver, error) {
+
+	type issuePage struct {
+		instance *JiraConfig
+		matcher  *regexp.Regexp
+	}
+	pages := []issuePage{}
+
+	for _, c := range config.Jira.Instances {
+		options, err := UnderlayOptions(&config.Jira.Options, &c.Options)
+		if err != nil {
+			return LogseqResolver{}, errors.Wrap(err, "Couldn't merge GeneralOptions with InstanceOptions")
+		}
+		prefix := ""
+		if options.Outputs.Logseq.InstancePrefix != nil {
+			prefix = *options.Outputs.Logseq.InstancePrefix
+		}
+		for _, p := range c.Projects {
+			pages = append(pages, issuePage{
+				instance: c,
+				matcher:  regexp.MustCompile(`(?i)^` + regexp.QuoteMeta(prefix) + `(` + regexp.QuoteMeta(*p.Key) + `-[0-9]+)$`),
+			})
+		}
+	}
+
+	var blocks map[string]string // Block text by ID, read from the graph on the first block reference
+
+	return LogseqResolver{
+		IssueURL: func(page string) string {
+			for _, p := range pages {
+				if m := p.matcher.FindStringSubmatch(page); m != nil {
+					return IssueID(p.instance, strings.ToUpper(m[1]))
+				}
+			}
+			return ""
+		},
+		AccountID: UserAccountID,
+		BlockText: func(uuid string) string {
+			if blocks == nil {
+				blocks = GraphBlocks(*config.Jira.Options.Outputs.Logseq.LogseqRoot)
+			}
+			return blocks[uuid]
+		},
+	}, nil
+}
+
+var graphBlockID = regexp.MustCompile(`^[0-9a-f-]{36}$`)
+
+// GraphBlocks reads the first line of every block with an ID from a graph's pages and journals
+func GraphBlocks(root string) map[string]string {
+
+	blocks := map[string]string{}
+
+	var add func(b *logseqBlock)
+	add = func(b *logseqBlock) {
+		for _, p := range b.properties {
+			if p.Key == "id" && graphBlockID.MatchString(p.Value) {
+				blocks[p.Value] = b.lines[0]
+			}
+		}
+		for _, child := range b.children {
+			add(child)
+		}
+	}
+
+	for _, dir := range []string{"pages", "journals"} {
+		_ = filepath.WalkDir(filepath.Join(root, dir), func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || filepath.Ext(path) != ".md" {
+				return nil
+			}
+			raw, err := ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			for _, b := range ParseLogseqPage(string(raw)).Blocks {
+				add(b)
+			}
+			return nil
+		})
+	}
+
+	return blocks
+}
+
+// wikiWriter renders the tree back into wiki markup
+type wikiWriter struct {
+	lineBreak string // How a break within text is written, where a newline would end the block
+	inTable   bool
+}
+
+// RenderWiki renders a parsed document as Jira wiki markup
+func RenderWiki(doc *WikiNode) string {
+	w := &wikiWriter{lineBreak: "\n"}
+	return w.blocks(doc.Children)
+}
+
+func (w *wikiWriter) blocks(nodes []*WikiNode) string {
+	parts := []string{}
+	for _, n := range nodes {
+		parts = append(parts, w.block(n))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func (w *wikiWriter) block(n *WikiNode) string {
+	switch n.Kind {
+	case WikiParagraph:
+		return w.lines(w.inline(n.Children))
+	case WikiHeading:
+		return fmt.Sprintf("h%d. ", n.Level) + w.with(`\\`, n.Children)
+	case WikiList:
+		return strings.Join(w.list(n, ""), "\n")
+	case WikiCode:
+		if language := n.Params["language"]; language != "" {
+			return "{code:" + language + "}\n" + n.Text + "\n{code}"
+		}
+		return "{code}\n" + n.Text + "\n{code}"
+	case WikiNoFormat:
+		return "{noformat}\n" + n.Text + "\n{noformat}"
+	case WikiQuote:
+		return "{quote}\n" + w.blocks(n.Children) + "\n{quote}"
+	case WikiPanel:
+		open := n.Name
+		if title := n.Params["title"]; title != "" {
+			open += ":title=" + strings.NewReplacer("|", "", "}", "", "=", "").Replace(title)
+		}
+		return "{" + open + "}\n" + w.blocks(n.Children) + "\n{" + n.Name + "}"
+	case WikiTable:
+		return w.table(n)
+	case WikiRule:
+		return "----"
+	}
+	return w.inline([]*WikiNode{n})
+}
+
+// with renders text where a newline can't be used for a break
+func (w *wikiWriter) with(lineBreak string, nodes []*WikiNode) string {
+	previous := w.lineBreak
+	w.lineBreak = lineBreak
+	defer func() { w.lineBreak = previous }()
+	return w.inline(nodes)
+}
+
+// lines escapes the start of any line of text that would otherwise be taken for a block of its own
+func (w *wikiWriter) lines(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		if strings.TrimSpace(l) != "" && wikiBlockStart(l) {
+			trimmed := strings.TrimLeft(l, " \t")
+			lines[i] = l[:len(l)-len(trimmed)] + `\` + trimmed
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (w *wikiWriter) list(list *WikiNode, marker string) (lines []string) {
+	if list.Ordered {
+		marker += "#"
+	} else {
+		marker += "*"
+	}
+	for _, item := range list.Children {
+		first := true
+		for _, n := range item.Children {
+			switch {
+			case n.Kind == WikiList:
+				lines = append(lines, w.list(n, marker)...)
+			case first && n.Kind == WikiParagraph:
+				lines = append(lines, marker+" "+w.lines(w.inline(n.Children)))
+			default: // Lists can't hold other blocks, so they break the list
+				if first {
+					lines = append(lines, marker+" ")
+				}
+				lines = append(lines, w.block(n))
+			}
+			first = false
+		}
+	}
+	return lines
+}
+
+func (w *wikiWriter) table(table *WikiNode) string {
+	w.inTable = true
+	defer func() { w.inTable = false }()
+
+	rows := []string{}
+	for _, row := range table.Children {
+		b := strings.Builder{}
+		for _, cell := range row.Children {
+			bar := "|"
+			if cell.Header {
+				bar = "||"
+			}
+			b.WriteString(bar + w.cell(cell))
+			if cell == row.Children[len(row.Children)-1] {
+				b.WriteString(bar)
+			}
+		}
+		rows = append(rows, b.String())
+	}
+	return strings.Join(rows, "\n")
+}
+
+// cell renders a cell's blocks, lists on lines of their own and anything else joined by breaks
+func (w *wikiWriter) cell(cell *WikiNode) string {
+	b := strings.Builder{}
+	for i, n := range cell.Children {
+		if n.Kind == WikiList {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString(strings.Join(w.list(n, ""), "\n"))
+			continue
+		}
+		if i > 0 {
+			if cell.Children[i-1].Kind == WikiList {
+				b.WriteString("\n")
+			} else {
+				b.WriteString(` \\ `)
+			}
+		}
+		if n.Kind == WikiParagraph {
+			b.WriteString(w.with(`\\`, n.Children))
+		} else {
+			b.WriteString(w.block(n))
+		}
+	}
+	if b.Len() == 0 {
+		return " "
+	}
+	return b.String()
+}
+
+func (w *wikiWriter) inline(nodes []*WikiNode) string {
+
+	b := strings.Builder{}
+
+	wrap := func(mark string, n *WikiNode) { // Marks must sit against the text they format
+		inner := w.inline(n.Children)
+		trimmed := strings.TrimSpace(inner)
+		if trimmed == "" {
+			b.WriteString(inner)
+			return
+		}
+		start := strings.Index(inner, trimmed)
+		b.WriteString(inner[:start] + mark + trimmed + mark + inner[start+len(trimmed):])
+	}
+
+	for _, n := range nodes {
+		switch n.Kind {
+		case WikiText:
+			b.WriteString(w.text(n.Text))
+		case WikiEscape:
+			b.WriteString(w.text(n.Text))
+		case WikiBreak:
+			b.WriteString(w.lineBreak)
+		case WikiStrong:
+			wrap("*", n)
+		case WikiEmphasis:
+			wrap("_", n)
+		case WikiDeleted:
+			wrap("-", n)
+		case WikiInserted:
+			wrap("+", n)
+		case WikiSuperscript:
+			wrap("^", n)
+		case WikiSubscript:
+			wrap("~", n)
+		case WikiCitation:
+			wrap("??", n)
+		case WikiColor:
+			b.WriteString("{color:" + n.Params["color"] + "}" + w.inline(n.Children) + "{color}")
+		case WikiMonospace:
+			if strings.Contains(n.Text, "}}") {
+				b.WriteString("{noformat}" + n.Text + "{noformat}")
+			} else {
+				b.WriteString("{{" + n.Text + "}}")
+			}
+		case WikiLink:
+			label := w.inline(n.Children)
+			if label == "" || label == n.Text {
+				b.WriteString("[" + n.Text + "]")
+			} else {
+				b.WriteString("[" + label + "|" + n.Text + "]")
+			}
+		case WikiMention:
+			b.WriteString("[~accountid:" + n.Text + "]")
+		case WikiAttachment:
+			label := w.inline(n.Children)
+			if label == "" || label == n.Text {
+				b.WriteString("[^" + n.Text + "]")
+			} else {
+				b.WriteString("[" + label + "|^" + n.Text + "]")
+			}
+		case WikiImage:
+			params := []string{}
+			for _, key := range []string{"width", "height"} {
+				if value := n.Params[key]; value != "" {
+					params = append(params, key+"="+value)
+				}
+			}
+			if len(params) > 0 {
+				b.WriteString("!" + n.Text + "|" + strings.Join(params, ",") + "!")
+			} else {
+				b.WriteString("!" + n.Text + "!")
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// text escapes what wiki markup would take for formatting.
+// Marks are escaped where they could open or close formatting, taking the text's ends as word boundaries.
+func (w *wikiWriter) text(s string) string {
+
+	b := strings.Builder{}
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		rest := s[i:]
+
+		switch {
+		case r == '{' || (r == '[' && strings.Contains(rest, "]")):
+			b.WriteString(`\` + string(r))
+		case r == '|' && w.inTable:
+			b.WriteString(`\|`)
+		case r == '!' && wikiImageMatcher.MatchString(rest):
+			b.WriteString(`\!`)
+		case strings.HasPrefix(rest, "??"):
+			b.WriteString(`\?\?`)
+			size = 2
+		case strings.HasPrefix(rest, "--"): // Dashes, not strikethrough
+			dashes := len(rest) - len(strings.TrimLeft(rest, "-"))
+			b.WriteString(rest[:dashes])
+			size = dashes
+		case strings.ContainsRune("*_-+^~", r):
+			prev, _ := utf8.DecodeLastRuneInString(s[:i])
+			next, _ := utf8.DecodeRuneInString(rest[size:])
+			atStart, atEnd := i == 0, i+size == len(s)
+			open := !atEnd && !unicode.IsSpace(next) && (atStart || !wikiWordRune(prev))
+			close := !atStart && !unicode.IsSpace(prev) && (atEnd || !wikiWordRune(next))
+			if open || close {
+				b.WriteString(`\`)
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteString(rest[:size])
+		}
+
+		i += size
+	}
+
+	return b.String()
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const testBlockID = "6512bd43-d9ca-4c4e-b84a-0c4b5e2d9f10"
+
+// testLogseqResolver resolves pages the way JiraRefs does, against a fixed instance, users from config and one block
+func testLogseqResolver(t *testing.T) LogseqResolver {
+	t.Helper()
+
+	previous := config.Jira.Users
+	t.Cleanup(func() { config.Jira.Users = previous })
+	if err := json.Unmarshal([]byte(`[{"account_id": "557058:abc", "display_name": "Jane Doe"}]`), &config.Jira.Users); err != nil {
+		t.Fatal(err)
+	}
+
+	issuePage := regexp.MustCompile(`^(PROJ-[0-9]+)$`)
+
+	return LogseqResolver{
+		IssueURL: func(page string) string {
+			if m := issuePage.FindStringSubmatch(page); m != nil {
+				return "https://jira.example.com/browse/" + m[1]
+			}
+			return ""
+		},
+		AccountID: UserAccountID,
+		BlockText: func(uuid string) string {
+			if uuid == testBlockID {
+				return "The **referenced** block"
+			}
+			return ""
+		},
+	}
+}
+
+func TestWikiRoundTrip(t *testing.T) {
+
+	resolver := testLogseqResolver(t)
+
+	tests := []struct {
+		name     string
+		wiki     string
+		expected string // Empty when it should come back unchanged
+	}{
+		{
+			name: "formatting",
+			wiki: "Some *bold*, _emphasis_, -deleted- and +inserted+ text with {{code}}",
+		},
+		{
+			name:     "issue link",
+			wiki:     "Blocked by https://jira.example.com/browse/PROJ-123",
+			expected: "Blocked by [https://jira.example.com/browse/PROJ-123]",
+		},
+		{
+			name:     "labelled issue link",
+			wiki:     "Blocked by [the other one|https://jira.example.com/browse/PROJ-124]",
+			expected: "Blocked by [the other one|https://jira.example.com/browse/PROJ-124]",
+		},
+		{
+			name: "mention",
+			wiki: "Over to [~accountid:557058:abc] for review",
+		},
+		{
+			name: "attachment",
+			wiki: "See [^build log.txt]",
+		},
+		{
+			name: "image",
+			wiki: "!screenshot.png|width=300!",
+		},
+		{
+			name: "code",
+			wiki: "{code:go}\nfmt.Println(\"*not bold*\")\n{code}",
+		},
+		{
+			name: "nested lists",
+			wiki: "Steps:\n* First\n** Nested\n**# Numbered\n* Second",
+			// Top level list items are blocks, which come back as paragraphs, so the list is nested under one
+			expected: "Steps:\n\n* First\n** Nested\n**# Numbered\n* Second",
+		},
+		{
+			name: "table",
+			wiki: "||Name||Status||\n|Alpha|*Done*|\n|Beta|In progress|",
+		},
+		{
+			name: "panel",
+			wiki: "{warning}\nCareful now.\n{warning}",
+		},
+		{
+			name:     "note panel",
+			wiki:     "{note}\nRemember this.\n{note}",
+			expected: "{info}\nRemember this.\n{info}", // Both are NOTE in Logseq
+		},
+		{
+			name:     "escapes",
+			wiki:     `Not \*bold\* and not a \[link\]`,
+			expected: `Not \*bold\* and not a \[link]`, // A closing bracket alone needs no escape
+		},
+		{
+			name: "task marker",
+			wiki: "DONE when this is merged\nTODO stays text mid block",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lines, err := RenderWikiBlocks(ParseWiki(test.wiki), testResolver)
+			if err != nil {
+				t.Fatal(err)
+			}
+			markdown := strings.Join(lines, "\n")
+
+			output := RenderWiki(ParseLogseq(markdown, resolver))
+
+			expected := test.expected
+			if expected == "" {
+				expected = test.wiki
+			}
+			if output != expected {
+				t.Errorf("round trip through\n%s\ngot      %q\nexpected %q", markdown, output, expected)
+			}
+		})
+	}
+}
+
+func TestLogseqToWiki(t *testing.T) {
+
+	resolver := testLogseqResolver(t)
+
+	tests := []struct {
+		name     string
+		markdown string
+		expected string
+	}{
+		{
+			name:     "issue page",
+			markdown: "- Blocked by [[PROJ-123]]",
+			expected: "Blocked by [https://jira.example.com/browse/PROJ-123]",
+		},
+		{
+			name:     "person page",
+			markdown: "- Over to [[Jane Doe]]",
+			expected: "Over to [~accountid:557058:abc]",
+		},
+		{
+			name:     "other page",
+			markdown: "- Part of [[Roadmap]]",
+			expected: "Part of Roadmap",
+		},
+		{
+			name:     "block reference",
+			markdown: "- As in ((" + testBlockID + "))",
+			expected: "As in The *referenced* block",
+		},
+		{
+			name:     "unknown block reference",
+			markdown: "- As in ((00000000-0000-0000-0000-000000000000))",
+			expected: "As in ((00000000-0000-0000-0000-000000000000))",
+		},
+		{
+			name:     "asset image",
+			markdown: "- ![screenshot.png](../assets/jira/jira_screenshot.png){:width 300}",
+			expected: "!screenshot.png|width=300!",
+		},
+		{
+			name:     "asset link",
+			markdown: "- [build log.txt](../assets/jira/jira_build_log.txt)",
+			expected: "[^build log.txt]",
+		},
+		{
+			name:     "nested blocks",
+			markdown: "- Parent\n\t- Child\n\t\t- Grandchild",
+			expected: "Parent\n\n* Child\n** Grandchild",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if output := RenderWiki(ParseLogseq(test.markdown, resolver)); output != test.expected {
+				t.Errorf("got %q, expected %q", output, test.expected)
+			}
+		})
+	}
+}
+
+func TestLogseqToADF(t *testing.T) {
+
+	resolver := testLogseqResolver(t)
+
+	tests := []struct {
+		name     string
+		markdown string
+		expected []string // Fragments of the JSON
+	}{
+		{
+			name:     "issue page",
+			markdown: "- Blocked by [[PROJ-123]]",
+			expected: []string{`"type":"inlineCard"`, `"url":"https://jira.example.com/browse/PROJ-123"`},
+		},
+		{
+			name:     "person page",
+			markdown: "- Over to [[Jane Doe]]",
+			expected: []string{`"type":"mention"`, `"id":"557058:abc"`},
+		},
+		{
+			name:     "block reference",
+			markdown: "- As in ((" + testBlockID + "))",
+			expected: []string{`"text":"referenced","marks":[{"type":"strong"}]`},
+		},
+		{
+			name:     "asset image",
+			markdown: "- ![screenshot.png](../assets/jira/jira_screenshot.png)",
+			expected: []string{`"type":"mediaSingle"`, `"type":"media"`, `screenshot.png`},
+		},
+		{
+			name:     "code",
+			markdown: "- ```go\n  x := *y\n  ```",
+			expected: []string{`"type":"codeBlock"`, `"language":"go"`, `"text":"x := *y"`},
+		},
+		{
+			name:     "panel",
+			markdown: "- #+BEGIN_WARNING\n  Careful now.\n  #+END_WARNING",
+			expected: []string{`"type":"panel"`, `"panelType":"warning"`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output, err := RenderADF(ParseLogseq(test.markdown, resolver))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc map[string]any
+			if err := json.Unmarshal(output, &doc); err != nil {
+				t.Fatalf("invalid ADF: %v", err)
+			}
+			for _, fragment := range test.expected {
+				if !strings.Contains(string(output), fragment) {
+					t.Errorf("%s missing from %s", fragment, output)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira/v2/cloud"
+	"github.com/pkg/errors"
+)
+
+var (
+	userDirectoryPath string
+	userMisses        = map[string]bool{} // Account IDs a search failed for this run, so they aren't searched for again
+)
+
+// LoadUsers reads the directory of users seen on earlier runs from the cache, so mentions resolve without searching
+func LoadUsers() {
+
+	userDirectoryPath = strings.Join([]string{*config.Jira.Options.Paths.CacheRoot, "users"}, "/") + ".json"
+
+	byteValue, err := ReadCacheFile(userDirectoryPath)
+	if err != nil {
+		slog.Warn("Failed to find or open file for users, assuming it hasn't been created yet")
+		return
+	}
+
+	usersLock.Lock()
+	defer usersLock.Unlock()
+
+	err = json.Unmarshal(byteValue, &users)
+	if err != nil {
+		slog.Warn("Failed to unmarshal users, starting afresh: " + err.Error())
+		users = map[string]string{}
+	}
+}
+
+// SaveUsers writes the directory of users back to the cache
+func SaveUsers() error {
+
+	usersLock.Lock()
+	jsonBytes, err := json.MarshalIndent(users, "", "  ")
+	usersLock.Unlock()
+	if err != nil {
+		return errors.Wrap(err, "Failed in json.Marshal")
+	}
+
+	return errors.Wrap(WriteCacheFile(userDirectoryPath, jsonBytes), "Failed in write file "+userDirectoryPath)
+}
+
+// RememberUser adds a user to the directory
+func RememberUser(u *jira.User) {
+	if u == nil || u.AccountID == "" || u.DisplayName == "" {
+		return
+	}
+	usersLock.Lock()
+	users[u.AccountID] = u.DisplayName
+	usersLock.Unlock()
+}
+
+// RememberIssueUsers adds everyone on an issue to the directory, its assignee, reporter, creator and comment authors
+func RememberIssueUsers(issue *jira.Issue) {
+	if issue == nil || issue.Fields == nil {
+		return
+	}
+	RememberUser(issue.Fields.Assignee)
+	RememberUser(issue.Fields.Reporter)
+	RememberUser(issue.Fields.Creator)
+	if issue.Fields.Comments != nil {
+		for _, comment := range issue.Fields.Comments.Comments {
+			if comment == nil {
+				continue
+			}
+			RememberUser(comment.Author)
+			RememberUser(comment.UpdateAuthor)
+		}
+	}
+}
+
+// UserAccountID returns the account ID of a person by their display name, or page name, or empty if they aren't known
+func UserAccountID(name string) string {
+
+	for _, u := range config.Jira.Users {
+		if strings.EqualFold(u.DisplayName, name) || strings.EqualFold(PersonName(u.DisplayName), name) {
+			return u.AccountID
+		}
+	}
+
+	usersLock.Lock()
+	defer usersLock.Unlock()
+
+	for id, displayName := range users {
+		if strings.EqualFold(displayName, name) || strings.EqualFold(PersonName(displayName), name) {
+			return id
+		}
+	}
+
+	return ""
+}
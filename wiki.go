@@ -0,0 +1,720 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// WikiKind is the kind of a node parsed from Jira wiki markup
+type WikiKind int
+
+const (
+	WikiDocument WikiKind = iota
+
+	// Blocks
+	WikiParagraph
+	WikiHeading  // Level 1 to 6
+	WikiList     // Of WikiListItems, Ordered or not
+	WikiListItem // A WikiParagraph, then any nested WikiLists
+	WikiCode     // Text is the code, Params["language"] its language if given
+	WikiNoFormat // Text is the preformatted text
+	WikiQuote    // bq. or {quote}
+	WikiPanel    // {panel} and the like, Name is the macro and Params its parameters
+	WikiTable    // Of WikiRows
+	WikiRow      // Of WikiCells
+	WikiCell     // Of blocks, a header cell if Header
+	WikiRule     // ----
+
+	// Inlines
+	WikiText
+	WikiEscape // Text is a character escaped with a backslash
+	WikiBreak  // \\ or a newline within a paragraph
+	WikiStrong
+	WikiEmphasis
+	WikiDeleted
+	WikiInserted
+	WikiSuperscript
+	WikiSubscript
+	WikiCitation
+	WikiMonospace  // Text is the literal text
+	WikiColor      // Params["color"] is the color
+	WikiLink       // Text is the URL, any children the link text
+	WikiMention    // Text is the account ID, or username on older instances
+	WikiAttachment // Text is the attachment's filename, any children the link text
+	WikiImage      // Text is the attachment's filename or a URL, Params its display parameters
+
+	// Logseq references, only kept when converting between Logseq's own formats
+	WikiPageRef  // Text is the page, any children the link text, Name is "#" if it was written as a tag
+	WikiBlockRef // Text is the block's UUID
+	WikiMacro    // Text is the whole macro, like {{embed [[page]]}}
+)
+
+// WikiNode is a node parsed from Jira wiki markup
+type WikiNode struct {
+	Kind     WikiKind
+	Text     string
+	Name     string
+	Level    int
+	Ordered  bool
+	Header   bool
+	Params   map[string]string
+	Children []*WikiNode
+}
+
+var (
+	wikiHeadingMatcher = regexp.MustCompile(`^\s*h([1-6])\.\s*(.*)$`)
+	wikiQuoteMatcher   = regexp.MustCompile(`^\s*bq\.\s*(.*)$`)
+	wikiListMatcher    = regexp.MustCompile(`^\s*([*#]+|-)\s+(.*)$`)
+	wikiRuleMatcher    = regexp.MustCompile(`^\s*-{4,}\s*$`)
+	wikiMacroMatcher   = regexp.MustCompile(`^\s*\{(code|noformat|quote|panel|info|note|warning|tip)(?::([^}]*))?\}(.*)$`)
+	wikiColorMatcher   = regexp.MustCompile(`^\{color(?::([^}]*))?\}`)
+	wikiInlineMacro    = regexp.MustCompile(`^\{(code|noformat)(?::[^}]*)?\}`)
+	wikiAnchorMatcher  = regexp.MustCompile(`^\{anchor(?::[^}]*)?\}`)
+	wikiImageMatcher   = regexp.MustCompile(`^!([^\s!|][^!\n]*?)!`)
+	wikiURLMatcher     = regexp.MustCompile(`^(?:(?:https?|ftp|file)://|mailto:)[^\s\[\]|<>"]+`)
+)
+
+// Wiki markup's inline formatting, by its mark
+var wikiMarks = map[string]WikiKind{
+	"*":  WikiStrong,
+	"_":  WikiEmphasis,
+	"-":  WikiDeleted,
+	"+":  WikiInserted,
+	"^":  WikiSuperscript,
+	"~":  WikiSubscript,
+	"??": WikiCitation,
+}
+
+// Jira's emoticons as Unicode, longest first so `(*r)` isn't taken for `(*)`
+var wikiEmoticons = []struct{ emoticon, unicode string }{
+	{"(flagoff)", "🏳️"},
+	{"(flag)", "🚩"},
+	{"(off)", "💡"},
+	{"(on)", "💡"},
+	{"(*r)", "⭐"},
+	{"(*g)", "⭐"},
+	{"(*b)", "⭐"},
+	{"(*y)", "⭐"},
+	{"(*)", "⭐"},
+	{"(y)", "👍"},
+	{"(n)", "👎"},
+	{"(i)", "ℹ️"},
+	{"(/)", "✅"},
+	{"(x)", "❌"},
+	{"(!)", "⚠️"},
+	{"(+)", "➕"},
+	{"(-)", "➖"},
+	{"(?)", "❓"},
+	{":)", "🙂"},
+	{":(", "🙁"},
+	{":P", "😛"},
+	{":D", "😀"},
+	{";)", "😉"},
+}
+
+// wikiEmoticon returns the Unicode for an emoticon at the start of s, standing apart from any word around it
+func wikiEmoticon(s string, prev rune, atStart bool) (emoticon string, replacement string) {
+	if !atStart && wikiWordRune(prev) {
+		return "", ""
+	}
+	for _, e := range wikiEmoticons {
+		if strings.HasPrefix(s, e.emoticon) {
+			next, _ := utf8.DecodeRuneInString(s[len(e.emoticon):])
+			if len(s) > len(e.emoticon) && wikiWordRune(next) {
+				return "", ""
+			}
+			return e.emoticon, e.unicode
+		}
+	}
+	return "", ""
+}
+
+// ParseWiki parses Jira wiki markup into a WikiDocument
+func ParseWiki(input string) *WikiNode {
+	input = strings.ReplaceAll(input, "\r\n", "\n")
+	return &WikiNode{Kind: WikiDocument, Children: parseWikiBlocks(strings.Split(input, "\n"))}
+}
+
+// wikiBlockStart returns whether a line starts a block other than a paragraph
+func wikiBlockStart(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" ||
+		strings.HasPrefix(trimmed, "|") ||
+		wikiHeadingMatcher.MatchString(line) ||
+		wikiQuoteMatcher.MatchString(line) ||
+		wikiRuleMatcher.MatchString(line) ||
+		wikiListMatcher.MatchString(line) ||
+		wikiMacroMatcher.MatchString(line)
+}
+
+func parseWikiBlocks(input []string) (blocks []*WikiNode) {
+
+	lines := slices.Clone(input) // Lines are rewritten when a macro closes partway through one
+
+	paragraph := []string{}
+	flush := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, &WikiNode{Kind: WikiParagraph, Children: ParseWikiInline(strings.Join(paragraph, "\n"))})
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+
+		if m := wikiMacroMatcher.FindStringSubmatch(line); m != nil {
+			flush()
+
+			name, params := m[1], parseWikiParams(m[2])
+			body, after, end := wikiMacroBody(name, m[3], lines, i)
+
+			switch name {
+			case "code":
+				if language, ok := params[""]; ok {
+					params["language"] = language
+				}
+				blocks = append(blocks, &WikiNode{Kind: WikiCode, Text: body, Params: params})
+			case "noformat":
+				blocks = append(blocks, &WikiNode{Kind: WikiNoFormat, Text: body, Params: params})
+			case "quote":
+				blocks = append(blocks, &WikiNode{Kind: WikiQuote, Children: parseWikiBlocks(strings.Split(body, "\n"))})
+			default:
+				blocks = append(blocks, &WikiNode{Kind: WikiPanel, Name: name, Params: params, Children: parseWikiBlocks(strings.Split(body, "\n"))})
+			}
+
+			i = end
+			if strings.TrimSpace(after) != "" {
+				lines[i] = after
+				i--
+			}
+			continue
+		}
+
+		if m := wikiHeadingMatcher.FindStringSubmatch(line); m != nil {
+			flush()
+			blocks = append(blocks, &WikiNode{Kind: WikiHeading, Level: int(m[1][0] - '0'), Children: ParseWikiInline(m[2])})
+			continue
+		}
+
+		if m := wikiQuoteMatcher.FindStringSubmatch(line); m != nil {
+			flush()
+			blocks = append(blocks, &WikiNode{Kind: WikiQuote, Children: []*WikiNode{{Kind: WikiParagraph, Children: ParseWikiInline(m[1])}}})
+			continue
+		}
+
+		if wikiRuleMatcher.MatchString(line) {
+			flush()
+			blocks = append(blocks, &WikiNode{Kind: WikiRule})
+			continue
+		}
+
+		if wikiListMatcher.MatchString(line) {
+			flush()
+			items := []wikiListLine{}
+			for ; i < len(lines); i++ {
+				if m := wikiListMatcher.FindStringSubmatch(lines[i]); m != nil {
+					items = append(items, wikiListLine{marker: m[1], text: m[2]})
+				} else if !wikiBlockStart(lines[i]) { // Continues the item before
+					items[len(items)-1].text += "\n" + lines[i]
+				} else {
+					break
+				}
+			}
+			i--
+			blocks = append(blocks, buildWikiLists(items, ParseWikiInline)...)
+			continue
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(line), "|") {
+			flush()
+			table := &WikiNode{Kind: WikiTable}
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				row := strings.TrimSpace(lines[i])
+				for !wikiRowEnded(row) && i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" && !strings.HasPrefix(strings.TrimSpace(lines[i+1]), "|") { // A cell running onto the next line
+					i++
+					row += "\n" + lines[i]
+				}
+				table.Children = append(table.Children, &WikiNode{Kind: WikiRow, Children: splitWikiRow(row)})
+			}
+			i--
+			blocks = append(blocks, table)
+			continue
+		}
+
+		paragraph = append(paragraph, line)
+	}
+
+	flush()
+
+	return blocks
+}
+
+// wikiMacroBody collects a macro's body from what follows its opening tag up to its closing tag.
+// It returns the body, whatever follows the closing tag on its line, and the index of that line.
+func wikiMacroBody(name string, rest string, lines []string, i int) (body string, after string, end int) {
+
+	closing := "{" + name + "}"
+
+	if j := strings.Index(rest, closing); j >= 0 {
+		return rest[:j], rest[j+len(closing):], i
+	}
+
+	parts := []string{rest}
+	for end = i + 1; end < len(lines); end++ {
+		if j := strings.Index(lines[end], closing); j >= 0 {
+			parts = append(parts, lines[end][:j])
+			after = lines[end][j+len(closing):]
+			break
+		}
+		parts = append(parts, lines[end])
+	}
+	if end == len(lines) { // Never closed, so it runs to the end
+		end--
+	}
+
+	if strings.TrimSpace(parts[0]) == "" { // The opening tag had a line to itself
+		parts = parts[1:]
+	}
+	if len(parts) > 0 && strings.TrimSpace(parts[len(parts)-1]) == "" { // So did the closing one
+		parts = parts[:len(parts)-1]
+	}
+
+	return strings.Join(parts, "\n"), after, end
+}
+
+// parseWikiParams parses macro parameters like `title=Notes|borderStyle=solid`, a bare one is keyed by ""
+func parseWikiParams(raw string) map[string]string {
+	params := map[string]string{}
+	for _, part := range strings.Split(raw, "|") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		key, value, found := strings.Cut(part, "=")
+		if !found {
+			params[""] = strings.TrimSpace(part)
+			continue
+		}
+		params[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return params
+}
+
+// wikiListLine is a list item's marker, like `*#`, and text
+type wikiListLine struct {
+	marker string
+	text   string
+}
+
+// buildWikiLists nests list items by the length of their markers, parsing their text with inline.
+// A change between ordered and unordered at the same depth starts a new list.
+func buildWikiLists(items []wikiListLine, inline func(string) []*WikiNode) (roots []*WikiNode) {
+
+	stack := []*WikiNode{} // The open list at each depth
+
+	attach := func(depth int, list *WikiNode) {
+		if depth == 0 {
+			roots = append(roots, list)
+			return
+		}
+		parent := stack[depth-1]
+		if len(parent.Children) == 0 { // Skipped a level, so there is no item to nest under yet
+			parent.Children = append(parent.Children, &WikiNode{Kind: WikiListItem, Children: []*WikiNode{{Kind: WikiParagraph}}})
+		}
+		item := parent.Children[len(parent.Children)-1]
+		item.Children = append(item.Children, list)
+	}
+
+	for _, item := range items {
+		depth := len(item.marker)
+		ordered := strings.HasSuffix(item.marker, "#")
+
+		if len(stack) > depth {
+			stack = stack[:depth]
+		}
+
+		for len(stack) < depth {
+			list := &WikiNode{Kind: WikiList, Ordered: item.marker[len(stack)] == '#'}
+			attach(len(stack), list)
+			stack = append(stack, list)
+		}
+
+		if stack[depth-1].Ordered != ordered {
+			list := &WikiNode{Kind: WikiList, Ordered: ordered}
+			attach(depth-1, list)
+			stack[depth-1] = list
+		}
+
+		list := stack[depth-1]
+		list.Children = append(list.Children, &WikiNode{Kind: WikiListItem, Children: []*WikiNode{{Kind: WikiParagraph, Children: inline(item.text)}}})
+	}
+
+	return roots
+}
+
+// wikiRowEnded returns whether a table row ends in a bar, rows that don't carry on onto the next line
+func wikiRowEnded(row string) bool {
+	row = strings.TrimSpace(row)
+	return strings.HasSuffix(row, "|") && !strings.HasSuffix(row, `\|`)
+}
+
+// splitWikiRow splits a table row into its cells, leaving alone the bars in links, images and monospace.
+// Cells hold blocks, as they can have several paragraphs or lists.
+func splitWikiRow(row string) (cells []*WikiNode) {
+
+	start := -1
+	header := false
+
+	cell := func(text string) *WikiNode {
+		return &WikiNode{Kind: WikiCell, Header: header, Children: parseWikiBlocks(strings.Split(strings.TrimSpace(text), "\n"))}
+	}
+
+	for i := 0; i < len(row); {
+		switch {
+		case row[i] == '\\' && i+1 < len(row):
+			i += 2
+			continue
+		case strings.HasPrefix(row[i:], "{{"):
+			if j := strings.Index(row[i+2:], "}}"); j >= 0 {
+				i += j + 4
+				continue
+			}
+		case row[i] == '[':
+			if j := strings.IndexByte(row[i:], ']'); j >= 0 {
+				i += j + 1
+				continue
+			}
+		case row[i] == '!':
+			if m := wikiImageMatcher.FindString(row[i:]); m != "" {
+				i += len(m)
+				continue
+			}
+		case row[i] == '|':
+			if start >= 0 {
+				cells = append(cells, cell(row[start:i]))
+			}
+			header = strings.HasPrefix(row[i:], "||")
+			if header {
+				i++
+			}
+			i++
+			start = i
+			continue
+		}
+		i++
+	}
+
+	if start >= 0 && strings.TrimSpace(row[start:]) != "" {
+		cells = append(cells, cell(row[start:]))
+	}
+
+	return cells
+}
+
+// wikiTokenKind is the kind of a token within a paragraph
+type wikiTokenKind int
+
+const (
+	wikiTokenText  wikiTokenKind = iota
+	wikiTokenNode                // Complete in itself, like a link or monospace
+	wikiTokenMark                // May open or close formatting
+	wikiTokenColor               // {color:...}, or {color} to close it
+)
+
+type wikiToken struct {
+	kind  wikiTokenKind
+	text  string
+	node  *WikiNode
+	open  bool // Whether a mark can open formatting, or a color tag opens a color
+	close bool // Whether a mark can close formatting
+}
+
+// ParseWikiInline parses the text of a paragraph, heading, list item or cell
+func ParseWikiInline(input string) []*WikiNode {
+	return parseWikiTokens(tokenizeWikiInline(input))
+}
+
+func wikiWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func tokenizeWikiInline(s string) (tokens []wikiToken) {
+
+	text := strings.Builder{}
+	flush := func() {
+		if text.Len() > 0 {
+			tokens = append(tokens, wikiToken{kind: wikiTokenText, text: text.String()})
+			text.Reset()
+		}
+	}
+	emit := func(t wikiToken) {
+		flush()
+		tokens = append(tokens, t)
+	}
+
+	for i := 0; i < len(s); {
+		rest := s[i:]
+
+		switch {
+		case strings.HasPrefix(rest, `\\`):
+			emit(wikiToken{kind: wikiTokenNode, node: &WikiNode{Kind: WikiBreak}})
+			i += 2
+			continue
+
+		case rest[0] == '\\' && len(rest) > 1:
+			r, size := utf8.DecodeRuneInString(rest[1:])
+			emit(wikiToken{kind: wikiTokenNode, node: &WikiNode{Kind: WikiEscape, Text: string(r)}})
+			i += 1 + size
+			continue
+
+		case rest[0] == '\n':
+			emit(wikiToken{kind: wikiTokenNode, node: &WikiNode{Kind: WikiBreak}})
+			i++
+			continue
+
+		case strings.HasPrefix(rest, "{{"):
+			if j := strings.Index(rest[2:], "}}"); j > 0 {
+				emit(wikiToken{kind: wikiTokenNode, node: &WikiNode{Kind: WikiMonospace, Text: rest[2 : 2+j]}})
+				i += j + 4
+				continue
+			}
+
+		case rest[0] == '{':
+			if m := wikiColorMatcher.FindStringSubmatch(rest); m != nil {
+				emit(wikiToken{kind: wikiTokenColor, text: m[1], open: m[1] != ""})
+				i += len(m[0])
+				continue
+			}
+			if m := wikiInlineMacro.FindStringSubmatch(rest); m != nil { // Code within a line
+				closing := "{" + m[1] + "}"
+				if j := strings.Index(rest[len(m[0]):], closing); j >= 0 {
+					emit(wikiToken{kind: wikiTokenNode, node: &WikiNode{Kind: WikiMonospace, Text: rest[len(m[0]) : len(m[0])+j]}})
+					i += len(m[0]) + j + len(closing)
+					continue
+				}
+			}
+			if m := wikiAnchorMatcher.FindString(rest); m != "" { // Anchors have nothing to show
+				i += len(m)
+				continue
+			}
+
+		case rest[0] == '[':
+			if j := strings.IndexAny(rest[1:], "]\n"); j > 0 && rest[1+j] == ']' {
+				if node := parseWikiLink(rest[1 : 1+j]); node != nil {
+					emit(wikiToken{kind: wikiTokenNode, node: node})
+					i += j + 2
+					continue
+				}
+			}
+
+		case rest[0] == '!':
+			if m := wikiImageMatcher.FindStringSubmatch(rest); m != nil && wikiImageSource(m[1]) {
+				emit(wikiToken{kind: wikiTokenNode, node: parseWikiImage(m[1])})
+				i += len(m[0])
+				continue
+			}
+		}
+
+		prev, _ := utf8.DecodeLastRuneInString(s[:i])
+		atStart := i == 0
+
+		if atStart || !wikiWordRune(prev) {
+			if url := wikiURLMatcher.FindString(rest); url != "" {
+				url = strings.TrimRight(url, ".,;:!?)'")
+				emit(wikiToken{kind: wikiTokenNode, node: &WikiNode{Kind: WikiLink, Text: url}})
+				i += len(url)
+				continue
+			}
+		}
+
+		if emoticon, replacement := wikiEmoticon(rest, prev, atStart); emoticon != "" {
+			text.WriteString(replacement)
+			i += len(emoticon)
+			continue
+		}
+
+		if strings.HasPrefix(rest, "--") { // Dashes, not strikethrough
+			dashes := len(rest) - len(strings.TrimLeft(rest, "-"))
+			text.WriteString(rest[:dashes])
+			i += dashes
+			continue
+		}
+
+		mark := ""
+		if strings.HasPrefix(rest, "??") {
+			mark = "??"
+		} else if _, ok := wikiMarks[rest[:1]]; ok {
+			mark = rest[:1]
+		}
+
+		if mark != "" {
+			next, _ := utf8.DecodeRuneInString(rest[len(mark):])
+			atEnd := len(rest) == len(mark)
+
+			t := wikiToken{
+				kind:  wikiTokenMark,
+				text:  mark,
+				open:  !atEnd && !unicode.IsSpace(next) && (atStart || !wikiWordRune(prev)),
+				close: !atStart && !unicode.IsSpace(prev) && (atEnd || !wikiWordRune(next)),
+			}
+			if t.open || t.close {
+				emit(t)
+			} else {
+				text.WriteString(mark)
+			}
+			i += len(mark)
+			continue
+		}
+
+		_, size := utf8.DecodeRuneInString(rest)
+		text.WriteString(rest[:size])
+		i += size
+	}
+
+	flush()
+
+	return tokens
+}
+
+// wikiImageSource returns whether the text between two exclamation marks looks like an image
+func wikiImageSource(s string) bool {
+	source, _, _ := strings.Cut(s, "|")
+	return strings.Contains(source, ".") || strings.Contains(source, "://")
+}
+
+// parseWikiImage parses the inside of `!image.png|width=200, height=100!`
+func parseWikiImage(s string) *WikiNode {
+	source, raw, _ := strings.Cut(s, "|")
+	params := map[string]string{}
+	for _, part := range strings.Split(raw, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(part, "=")
+		params[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return &WikiNode{Kind: WikiImage, Text: strings.TrimSpace(source), Params: params}
+}
+
+// parseWikiLink parses the inside of square brackets, or returns nil if they aren't a link
+func parseWikiLink(s string) *WikiNode {
+
+	label, target, named := strings.Cut(s, "|")
+	if !named {
+		target = s
+	}
+	target, _, _ = strings.Cut(target, "|") // Drop any tooltip
+	target = strings.TrimSpace(target)
+
+	var node *WikiNode
+
+	switch {
+	case strings.HasPrefix(target, "~"):
+		node = &WikiNode{Kind: WikiMention, Text: strings.TrimPrefix(target[1:], "accountid:")}
+	case strings.HasPrefix(target, "^"):
+		node = &WikiNode{Kind: WikiAttachment, Text: target[1:]}
+	case strings.HasPrefix(target, "#"): // Anchors within the page go nowhere once converted
+		if !named {
+			label = target[1:]
+		}
+		return &WikiNode{Kind: WikiText, Text: label}
+	case wikiURLMatcher.MatchString(target):
+		node = &WikiNode{Kind: WikiLink, Text: target}
+	case strings.HasPrefix(target, "www."):
+		node = &WikiNode{Kind: WikiLink, Text: "http://" + target}
+	default:
+		return nil
+	}
+
+	if named && node.Kind != WikiMention {
+		node.Children = ParseWikiInline(label)
+	}
+
+	return node
+}
+
+func parseWikiTokens(tokens []wikiToken) (nodes []*WikiNode) {
+
+	appendText := func(s string) {
+		if len(nodes) > 0 && nodes[len(nodes)-1].Kind == WikiText {
+			nodes[len(nodes)-1].Text += s
+			return
+		}
+		nodes = append(nodes, &WikiNode{Kind: WikiText, Text: s})
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+
+		switch t.kind {
+		case wikiTokenText:
+			appendText(t.text)
+
+		case wikiTokenNode:
+			if t.node.Kind == WikiText {
+				appendText(t.node.Text)
+			} else {
+				nodes = append(nodes, t.node)
+			}
+
+		case wikiTokenMark:
+			if t.open {
+				if j := wikiClosingMark(tokens, i); j > i+1 {
+					nodes = append(nodes, &WikiNode{Kind: wikiMarks[t.text], Children: parseWikiTokens(tokens[i+1 : j])})
+					i = j
+					continue
+				}
+			}
+			appendText(t.text)
+
+		case wikiTokenColor:
+			if t.open {
+				if j := wikiClosingColor(tokens, i); j > i {
+					nodes = append(nodes, &WikiNode{Kind: WikiColor, Params: map[string]string{"color": t.text}, Children: parseWikiTokens(tokens[i+1 : j])})
+					i = j
+				}
+			} // An unmatched tag has nothing to show
+		}
+	}
+
+	return nodes
+}
+
+// wikiClosingMark finds the mark closing the one at i, formatting doesn't carry across lines
+func wikiClosingMark(tokens []wikiToken, i int) int {
+	for j := i + 1; j < len(tokens); j++ {
+		t := tokens[j]
+		if t.kind == wikiTokenNode && t.node.Kind == WikiBreak {
+			return -1
+		}
+		if t.kind == wikiTokenMark && t.text == tokens[i].text && t.close {
+			return j
+		}
+	}
+	return -1
+}
+
+// wikiClosingColor finds the {color} closing the one at i, allowing for nested colors
+func wikiClosingColor(tokens []wikiToken, i int) int {
+	depth := 0
+	for j := i + 1; j < len(tokens); j++ {
+		if tokens[j].kind != wikiTokenColor {
+			continue
+		}
+		if tokens[j].open {
+			depth++
+		} else if depth == 0 {
+			return j
+		} else {
+			depth--
+		}
+	}
+	return -1
+}
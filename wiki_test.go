@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the current output")
+
+// testResolver resolves a fixed set of users, attachments and issues, so golden output doesn't depend on Jira
+var testResolver = WikiResolver{
+	Attachment: func(filename string) (string, error) { // Saved by ID, as SaveAttachment does, with no spaces
+		return "../assets/jira/jira_" + strings.ReplaceAll(filename, " ", "_"), nil
+	},
+	Mention: func(accountID string) string {
+		if accountID == "557058:abc" {
+			return "[[Jane Doe]]"
+		}
+		return accountID
+	},
+	IssueLink: func(url string) string {
+		if m := regexp.MustCompile(`^https://jira\.example\.com/browse/([A-Z]+-[0-9]+)$`).FindStringSubmatch(url); m != nil {
+			return m[1]
+		}
+		return ""
+	},
+}
+
+// golden compares output with a file in testdata, or rewrites the file when run with -update
+func golden(t *testing.T, path string, output string) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, []byte(output), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != string(expected) {
+		t.Errorf("output differs from %s\n--- got ---\n%s\n--- expected ---\n%s", path, output, expected)
+	}
+}
+
+func renderWikiFixture(t *testing.T, path string) string {
+	t.Helper()
+	input, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines, err := RenderWikiBlocks(ParseWiki(string(input)), testResolver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func wikiFixtures(t *testing.T) []string {
+	t.Helper()
+	inputs, err := filepath.Glob(filepath.Join("testdata", "wiki", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no wiki fixtures found")
+	}
+	return inputs
+}
+
+func TestWikiGolden(t *testing.T) {
+	for _, input := range wikiFixtures(t) {
+		name := strings.TrimSuffix(filepath.Base(input), ".txt")
+		t.Run(name, func(t *testing.T) {
+			golden(t, strings.TrimSuffix(input, ".txt")+".md", renderWikiFixture(t, input))
+		})
+	}
+}
+
+// Issues are processed concurrently, so rendering must not share state. Run with -race.
+func TestWikiParallel(t *testing.T) {
+	expected := map[string]string{}
+	for _, input := range wikiFixtures(t) {
+		expected[input] = renderWikiFixture(t, input)
+	}
+	for i := 0; i < 8; i++ {
+		for input, want := range expected {
+			t.Run(filepath.Base(input), func(t *testing.T) {
+				t.Parallel()
+				if got := renderWikiFixture(t, input); got != want {
+					t.Errorf("parallel render of %s differs\n--- got ---\n%s\n--- expected ---\n%s", input, got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestJiraToMDCode(t *testing.T) {
+	output := JiraToMD("{code}\n*bold* https://example.com\n{code}")
+	expected := "```\n*bold* https://example.com\n```"
+	if output != expected {
+		t.Errorf("got %q, expected %q", output, expected)
+	}
+}
+
+// ADF panels become the same admonitions as the wiki macros, errors being warnings
+func TestADFPanels(t *testing.T) {
+	for panelType, admonition := range map[string]string{
+		"info":    "NOTE",
+		"note":    "NOTE",
+		"warning": "WARNING",
+		"error":   "WARNING",
+		"success": "TIP",
+		"tip":     "TIP",
+		"custom":  "NOTE",
+	} {
+		doc := `{"type": "doc", "version": 1, "content": [{"type": "panel", "attrs": {"panelType": "` + panelType + `"},
+			"content": [{"type": "paragraph", "content": [{"type": "text", "text": "Body"}]}]}]}`
+		lines, err := RenderWikiBlocks(ParseJiraDocument(doc), testResolver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := "- #+BEGIN_" + admonition + "\n  Body\n  #+END_" + admonition
+		if output := strings.Join(lines, "\n"); output != expected {
+			t.Errorf("%s panel gave %q, expected %q", panelType, output, expected)
+		}
+	}
+}
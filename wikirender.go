@@ -0,0 +1,454 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/segmentio/fasthash/fnv1a"
+)
+
+// WikiResolver looks up what wiki markup only refers to. Any of its functions may be nil.
+type WikiResolver struct {
+	Attachment func(filename string) (string, error) // Path to link an attachment by
+	Mention    func(accountID string) string         // How to show a mentioned user
+	IssueLink  func(url string) string               // Page a URL to an issue links to, or empty if it isn't one
+	WideTable  func(columns int) string              // Prefix for the IDs of a table this wide shown as a table query, or empty for a Markdown table
+}
+
+// wikiRenderer renders parsed wiki markup as Logseq Markdown, keeping the first error a resolver returns
+type wikiRenderer struct {
+	resolver WikiResolver
+	err      error
+}
+
+// Logseq admonitions for Jira's panel macros
+var wikiAdmonitions = map[string]string{
+	"panel":   "NOTE",
+	"info":    "NOTE",
+	"note":    "NOTE",
+	"warning": "WARNING",
+	"tip":     "TIP",
+}
+
+// JiraToMD converts Jira wiki markup to Markdown, with nothing resolved
+func JiraToMD(input string) string {
+	r := &wikiRenderer{}
+	return r.document(ParseWiki(input))
+}
+
+// RenderWikiBlocks renders parsed wiki markup as Logseq blocks, one line per element.
+// Lists following a paragraph are nested under it.
+func RenderWikiBlocks(doc *WikiNode, resolver WikiResolver) ([]string, error) {
+	r := &wikiRenderer{resolver: resolver}
+	lines := r.blocks(doc.Children, 0)
+	return lines, r.err
+}
+
+// document renders blocks as plain Markdown, separated by blank lines
+func (r *wikiRenderer) document(doc *WikiNode) string {
+	parts := []string{}
+	for _, n := range doc.Children {
+		parts = append(parts, r.block(n))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// outlineBlock lays out Markdown as a single Logseq block, with any properties after its first line
+func outlineBlock(markdown string, depth int, properties ...string) []string {
+	tabs := strings.Repeat("\t", depth)
+	lines := strings.Split(markdown, "\n")
+	output := []string{tabs + "- " + LogseqBlockStart(lines[0])}
+	for _, p := range properties {
+		output = append(output, tabs+"  "+p)
+	}
+	for _, l := range lines[1:] {
+		output = append(output, tabs+"  "+l)
+	}
+	return output
+}
+
+func (r *wikiRenderer) blocks(nodes []*WikiNode, depth int) (lines []string) {
+	for i, n := range nodes {
+		switch n.Kind {
+		case WikiList:
+			d := depth
+			if i > 0 && nodes[i-1].Kind == WikiParagraph {
+				d++
+			}
+			lines = append(lines, r.listBlocks(n, d)...)
+		case WikiTable:
+			prefix := ""
+			if r.resolver.WideTable != nil {
+				prefix = r.resolver.WideTable(wikiColumns(n))
+			}
+			if prefix != "" {
+				lines = append(lines, r.queryTable(n, depth, prefix)...)
+			} else {
+				lines = append(lines, outlineBlock(r.block(n), depth)...)
+			}
+		default:
+			lines = append(lines, outlineBlock(r.block(n), depth)...)
+		}
+	}
+	return lines
+}
+
+func (r *wikiRenderer) listBlocks(list *WikiNode, depth int) (lines []string) {
+	for _, item := range list.Children {
+		for j, n := range item.Children {
+			if n.Kind == WikiList {
+				lines = append(lines, r.listBlocks(n, depth+1)...)
+				continue
+			}
+			properties := []string{}
+			if list.Ordered && j == 0 {
+				properties = append(properties, "logseq.order-list-type:: number")
+			}
+			lines = append(lines, outlineBlock(r.block(n), depth, properties...)...)
+		}
+	}
+	return lines
+}
+
+// block renders a single block as Markdown
+func (r *wikiRenderer) block(n *WikiNode) string {
+	switch n.Kind {
+	case WikiParagraph:
+		return r.inline(n.Children)
+	case WikiHeading:
+		return strings.Repeat("#", n.Level) + " " + r.inline(n.Children)
+	case WikiList:
+		return r.list(n, "")
+	case WikiCode:
+		return "```" + n.Params["language"] + "\n" + n.Text + "\n```"
+	case WikiNoFormat:
+		return "```\n" + n.Text + "\n```"
+	case WikiQuote:
+		return "#+BEGIN_QUOTE\n" + r.document(n) + "\n#+END_QUOTE"
+	case WikiPanel:
+		admonition, ok := wikiAdmonitions[n.Name]
+		if !ok {
+			admonition = "NOTE"
+		}
+		body := r.document(n)
+		if title := n.Params["title"]; title != "" {
+			body = "**" + markdownText(title) + "**\n" + body
+		}
+		return "#+BEGIN_" + admonition + "\n" + body + "\n#+END_" + admonition
+	case WikiTable:
+		return r.table(n)
+	case WikiRule:
+		return "---"
+	}
+	return r.inline([]*WikiNode{n})
+}
+
+// list renders a list as Markdown, numbering each ordered list from 1
+func (r *wikiRenderer) list(list *WikiNode, indent string) string {
+	lines := []string{}
+	for i, item := range list.Children {
+		marker := "* "
+		if list.Ordered {
+			marker = strconv.Itoa(i+1) + ". "
+		}
+		for j, n := range item.Children {
+			if n.Kind == WikiList {
+				lines = append(lines, r.list(n, indent+strings.Repeat(" ", len(marker))))
+				continue
+			}
+			for k, l := range strings.Split(r.block(n), "\n") {
+				if j == 0 && k == 0 {
+					lines = append(lines, indent+marker+l)
+				} else {
+					lines = append(lines, indent+strings.Repeat(" ", len(marker))+l)
+				}
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// table renders a table as a Markdown table. One without a header row gets an empty one, as Markdown needs it,
+// and header cells elsewhere, like those heading rows, are bold.
+func (r *wikiRenderer) table(table *WikiNode) string {
+
+	rows := table.Children
+	columns := wikiColumns(table)
+
+	lines := []string{}
+	if len(rows) > 0 && wikiHeaderRow(rows[0]) {
+		lines = append(lines, r.row(rows[0], columns, false))
+		rows = rows[1:]
+	} else {
+		lines = append(lines, "|"+strings.Repeat("   |", columns))
+	}
+	lines = append(lines, "|"+strings.Repeat(" --- |", columns))
+
+	for _, row := range rows {
+		lines = append(lines, r.row(row, columns, true))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (r *wikiRenderer) row(row *WikiNode, columns int, boldHeaders bool) string {
+	cells := []string{}
+	for _, cell := range row.Children {
+		text := strings.ReplaceAll(strings.ReplaceAll(r.cell(cell), `\|`, "|"), "|", `\|`)
+		text = strings.ReplaceAll(text, "\n", "<br>")
+		if cell.Header && boldHeaders && text != "" {
+			text = "**" + text + "**"
+		}
+		cells = append(cells, text)
+	}
+	for len(cells) < columns {
+		cells = append(cells, "")
+	}
+	return "| " + strings.Join(cells, " | ") + " |"
+}
+
+// cell renders the blocks of a cell a line each, with list items marked as they would be in a list
+func (r *wikiRenderer) cell(cell *WikiNode) string {
+	lines := []string{}
+	for _, n := range cell.Children {
+		if n.Kind == WikiList {
+			lines = append(lines, r.cellList(n, 0)...)
+		} else {
+			lines = append(lines, r.block(n))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (r *wikiRenderer) cellList(list *WikiNode, depth int) (lines []string) {
+	indent := strings.Repeat("\u2003", depth) // Em spaces, as leading spaces are dropped
+	for i, item := range list.Children {
+		marker := "• "
+		if list.Ordered {
+			marker = strconv.Itoa(i+1) + ". "
+		}
+		for j, n := range item.Children {
+			if n.Kind == WikiList {
+				lines = append(lines, r.cellList(n, depth+1)...)
+				continue
+			}
+			text := r.block(n)
+			if j == 0 {
+				text = marker + text
+			}
+			lines = append(lines, indent+text)
+		}
+	}
+	return lines
+}
+
+// queryTable renders a table as a block per row, with a property per column, under a query showing them as a table.
+// Rows are tagged with an ID made from the prefix and the table's contents, for the query to find them by.
+func (r *wikiRenderer) queryTable(table *WikiNode, depth int, prefix string) []string {
+
+	rows := table.Children
+	id := prefix + strconv.FormatUint(fnv1a.HashString64(r.table(table)), 36)
+
+	names := []string{}
+	if len(rows) > 0 && wikiHeaderRow(rows[0]) {
+		for _, cell := range rows[0].Children {
+			names = append(names, r.cell(cell))
+		}
+		rows = rows[1:]
+	}
+	properties := wikiColumnProperties(names, wikiColumns(table))
+
+	lines := outlineBlock(`{{query (property :jira-table "`+id+`")}}`, depth,
+		"query-table:: true",
+		"query-properties:: [:"+strings.Join(properties, " :")+"]",
+	)
+
+	for _, row := range rows {
+		values := []string{"jira-table:: " + id}
+		for i, cell := range row.Children {
+			if value := strings.ReplaceAll(r.cell(cell), "\n", " "); value != "" {
+				values = append(values, properties[i]+":: "+value)
+			}
+		}
+		lines = append(lines, outlineBlock(values[0], depth+1, values[1:]...)...)
+	}
+
+	return lines
+}
+
+// wikiColumns returns the number of cells in a table's widest row
+func wikiColumns(table *WikiNode) (columns int) {
+	for _, row := range table.Children {
+		columns = max(columns, len(row.Children))
+	}
+	return columns
+}
+
+// wikiHeaderRow returns whether a row is all header cells
+func wikiHeaderRow(row *WikiNode) bool {
+	for _, cell := range row.Children {
+		if !cell.Header {
+			return false
+		}
+	}
+	return len(row.Children) > 0
+}
+
+var wikiPropertyUnsafe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// wikiColumnProperties names a property for each column after its header, or its number if that won't do
+func wikiColumnProperties(headers []string, columns int) []string {
+	properties := []string{}
+	for i := 0; i < columns; i++ {
+		name := ""
+		if i < len(headers) {
+			name = strings.Trim(wikiPropertyUnsafe.ReplaceAllString(strings.ToLower(headers[i]), "-"), "-")
+		}
+		if name == "" || name == "jira-table" || slices.Contains(properties, name) {
+			name = "column-" + strconv.Itoa(i+1)
+		}
+		properties = append(properties, name)
+	}
+	return properties
+}
+
+// markdownText escapes what Logseq would otherwise take for maths, tags, references, macros or properties
+func markdownText(s string) string {
+	return LogseqText(strings.ReplaceAll(s, "$", `\$`))
+}
+
+func (r *wikiRenderer) inline(nodes []*WikiNode) string {
+
+	b := strings.Builder{}
+
+	wrap := func(open string, n *WikiNode, close string) { // Spaces at either end go outside, where Markdown wants them
+		inner := r.inline(n.Children)
+		trimmed := strings.TrimSpace(inner)
+		if trimmed == "" {
+			b.WriteString(inner)
+			return
+		}
+		start := strings.Index(inner, trimmed)
+		b.WriteString(inner[:start] + open + trimmed + close + inner[start+len(trimmed):])
+	}
+
+	for _, n := range nodes {
+		switch n.Kind {
+		case WikiText:
+			b.WriteString(markdownText(n.Text))
+		case WikiEscape:
+			if strings.Contains("\\`*_{}[]()#+-.!|~^$", n.Text) {
+				b.WriteString(`\`)
+			}
+			b.WriteString(n.Text)
+		case WikiBreak:
+			b.WriteString("\n")
+		case WikiStrong:
+			wrap("**", n, "**")
+		case WikiEmphasis:
+			wrap("*", n, "*")
+		case WikiDeleted:
+			wrap("~~", n, "~~")
+		case WikiInserted:
+			wrap("<ins>", n, "</ins>")
+		case WikiSuperscript:
+			wrap("<sup>", n, "</sup>")
+		case WikiSubscript:
+			wrap("<sub>", n, "</sub>")
+		case WikiCitation:
+			wrap("<cite>", n, "</cite>")
+		case WikiColor:
+			wrap("<span style='color: "+n.Params["color"]+"'>", n, "</span>")
+		case WikiMonospace:
+			fence := "`"
+			for strings.Contains(n.Text, fence) {
+				fence += "`"
+			}
+			if fence != "`" {
+				b.WriteString(fence + " " + n.Text + " " + fence)
+			} else {
+				b.WriteString(fence + n.Text + fence)
+			}
+		case WikiLink:
+			b.WriteString(r.link(n))
+		case WikiMention:
+			b.WriteString(r.mention(n.Text))
+		case WikiAttachment:
+			path := r.attachment(n.Text)
+			label := r.inline(n.Children)
+			if label == "" {
+				label = n.Text
+			}
+			b.WriteString("[" + label + "](" + path + ")")
+		case WikiImage:
+			b.WriteString(r.image(n))
+		}
+	}
+
+	return b.String()
+}
+
+func (r *wikiRenderer) link(n *WikiNode) string {
+	label := r.inline(n.Children)
+
+	if r.resolver.IssueLink != nil {
+		if page := r.resolver.IssueLink(n.Text); page != "" {
+			if label == "" || label == n.Text {
+				return "[[" + page + "]]"
+			}
+			return "[" + label + "]([[" + page + "]])"
+		}
+	}
+
+	if label == "" {
+		return n.Text
+	}
+	return "[" + label + "](" + n.Text + ")"
+}
+
+func (r *wikiRenderer) mention(accountID string) string {
+	if r.resolver.Mention != nil {
+		return r.resolver.Mention(accountID)
+	}
+	return accountID
+}
+
+// attachment returns the path to link an attachment by, or its filename if it can't be found
+func (r *wikiRenderer) attachment(filename string) string {
+	if r.resolver.Attachment == nil {
+		return filename
+	}
+	path, err := r.resolver.Attachment(filename)
+	if err != nil {
+		if r.err == nil {
+			r.err = err
+		}
+		return filename
+	}
+	return path
+}
+
+// image renders an image, with its width and height as Logseq's size attributes
+func (r *wikiRenderer) image(n *WikiNode) string {
+
+	source := n.Text
+	if !wikiURLMatcher.MatchString(source) {
+		source = r.attachment(n.Text)
+	}
+
+	output := "![" + n.Text + "](" + source + ")"
+
+	size := []string{}
+	for _, key := range []string{"width", "height"} {
+		if value, ok := n.Params[key]; ok && value != "" {
+			size = append(size, ":"+key+" "+strings.TrimSuffix(value, "px"))
+		}
+	}
+	if len(size) > 0 {
+		output += "{" + strings.Join(size, ", ") + "}"
+	}
+
+	return output
+}
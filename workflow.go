@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Logseq's task markers
+var logseqMarkers = []string{
+	"LATER", "NOW",
+	"TODO", "DOING",
+	"WAIT", "WAITING",
+	"IN-PROGRESS",
+	"CANCELED", "CANCELLED",
+	"DONE",
+}
+
+// Markers that differ between the :todo and :now workflows, as {todo, now}
+var workflowMarkers = [][2]string{
+	{"TODO", "LATER"},
+	{"DOING", "NOW"},
+}
+
+var (
+	preferredWorkflowMatcher = regexp.MustCompile(`:preferred-workflow\s+:(todo|now)\b`)
+	preferredWorkflows       = map[string]string{} // By graph root
+	preferredWorkflowsLock   = &sync.Mutex{}
+)
+
+// Markers meaning the work is over, done or not
+var closedMarkers = []string{"DONE", "CANCELED", "CANCELLED"}
+
+// ClosedMarker returns whether a marker means the work is over, done or not
+func ClosedMarker(marker string) bool {
+	return slices.Contains(closedMarkers, marker)
+}
+
+// PreferredWorkflow returns the :preferred-workflow of the graph a project writes to, "todo" or "now",
+// or empty if the graph has no config or doesn't set one
+func PreferredWorkflow(project *JiraProject) string {
+
+	root := *project.Options.Outputs.Logseq.LogseqRoot
+
+	preferredWorkflowsLock.Lock()
+	defer preferredWorkflowsLock.Unlock()
+
+	if workflow, ok := preferredWorkflows[root]; ok {
+		return workflow
+	}
+
+	workflow := ""
+
+	contents, err := ReadFile(root + "/logseq/config.edn")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("Failed to read the graph config for its :preferred-workflow: " + err.Error())
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), ";") // Without comments
+		if m := preferredWorkflowMatcher.FindStringSubmatch(line); m != nil {
+			workflow = m[1]
+			break
+		}
+	}
+
+	preferredWorkflows[root] = workflow
+	return workflow
+}
+
+// WorkflowMarker swaps a marker for its counterpart in the workflow set by `status.workflow`,
+// e.g. TODO becomes LATER for the :now workflow
+func WorkflowMarker(project *JiraProject, marker string) string {
+
+	workflow := ""
+	if project.Options.Status.Workflow != nil {
+		workflow = *project.Options.Status.Workflow
+	}
+	if workflow == "auto" {
+		workflow = PreferredWorkflow(project)
+	}
+
+	for _, pair := range workflowMarkers {
+		switch {
+		case workflow == "now" && marker == pair[0]:
+			return pair[1]
+		case workflow == "todo" && marker == pair[1]:
+			return pair[0]
+		}
+	}
+
+	return marker
+}
+
+// CheckMarkers warns about status mappings to anything Logseq won't treat as a task marker
+func CheckMarkers(project *JiraProject) {
+	s := project.Options.Status
+	markers := []*string{s.Default, s.Category.New, s.Category.Indeterminate, s.Category.Done}
+	for _, m := range s.Match {
+		markers = append(markers, m.To)
+	}
+	for _, m := range markers {
+		if m != nil && *m != "" && !slices.Contains(logseqMarkers, *m) {
+			slog.Warn("Status marker `" + *m + "` in project " + *project.Key + " isn't one of " + strings.Join(logseqMarkers, ", "))
+		}
+	}
+}